@@ -1,8 +1,9 @@
 module GoLeafServer
 
-go 1.12
+go 1.18
 
 require (
 	github.com/gorilla/websocket v1.4.0
 	github.com/name5566/leaf v0.0.0-20181103040206-1364c176dfbd
+	gopkg.in/yaml.v3 v3.0.1
 )
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"GoLeafServer/leafserver/src/server/gamedata"
+	"flag"
+	"fmt"
+	"os"
+)
+
+// 将配置目录打包为数据包，输出的 sha256 填入 GamedataChecksum
+var (
+	dir     = flag.String("dir", "gamedata", "gamedata directory")
+	out     = flag.String("o", "gamedata.zip", "bundle output file")
+	version = flag.String("version", "", "bundle version")
+)
+
+func main() {
+	flag.Parse()
+	if *version == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	f, err := os.Create(*out)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	sum, err := gamedata.WriteBundle(f, *dir, *version)
+	if err == nil {
+		err = f.Close()
+	} else {
+		f.Close()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Println(sum)
+}
@@ -2,6 +2,7 @@ package conf
 
 import (
 	"log"
+	"sync/atomic"
 	"time"
 )
 
@@ -10,11 +11,28 @@ var (
 	LogFlag = log.LstdFlags
 
 	// gate conf
-	PendingWriteNum        = 2000
-	MaxMsgLen       uint32 = 4096
-	HTTPTimeout            = 10 * time.Second
-	LenMsgLen              = 2
-	LittleEndian           = false
+	PendingWriteNum         = 2000
+	MaxMsgLen        uint32 = 4096
+	HTTPTimeout             = 10 * time.Second
+	LenMsgLen               = 2
+	LittleEndian            = false
+	HandshakeTimeout        = 10 * time.Second
+	// 默认不启用，不发送心跳的客户端不会被断开
+	IdleTimeout       = time.Duration(0)
+	HeartbeatInterval = time.Duration(0)
+
+	// gate rate limit conf，0 表示不限制
+	MaxConnPerIP    = 0
+	MsgRate         = 100.0
+	MsgBurst        = 200
+	ByteRate        = 64 * 1024
+	ByteBurst       = 128 * 1024
+	MsgTypeRates    map[string]float64
+	RateLimitAction = "drop"
+
+	// gate resume conf
+	ResumeGrace  = 30 * time.Second
+	ResumeBuffer = 256
 
 	// skeleton conf
 	GoLen              = 10000
@@ -22,3 +40,56 @@ var (
 	AsynCallLen        = 10000
 	ChanRPCLen         = 10000
 )
+
+// 全部配置项，字段名与配置项名相同，用于检查配置和读取当前生效的配置
+type Config struct {
+	LogLevel         string
+	LogPath          string
+	WSAddr           string
+	CertFile         string
+	KeyFile          string
+	TCPAddr          string
+	MaxConnNum       int
+	ConsolePort      int
+	ProfilePath      string
+	GamedataPath     string
+	GamedataBundle   string
+	GamedataChecksum string
+	AuthRequired     bool
+	AuthSecret       string
+	HandshakeMsgs    []string
+
+	LogFlag           int
+	PendingWriteNum   int
+	MaxMsgLen         uint32
+	HTTPTimeout       time.Duration
+	LenMsgLen         int
+	LittleEndian      bool
+	HandshakeTimeout  time.Duration
+	IdleTimeout       time.Duration
+	HeartbeatInterval time.Duration
+
+	MaxConnPerIP    int
+	MsgRate         float64
+	MsgBurst        int
+	ByteRate        int
+	ByteBurst       int
+	MsgTypeRates    map[string]float64
+	RateLimitAction string
+
+	ResumeGrace  time.Duration
+	ResumeBuffer int
+
+	GoLen              int
+	TimerDispatcherLen int
+	AsynCallLen        int
+	ChanRPCLen         int
+}
+
+var current atomic.Value
+
+// 当前生效的配置，重新加载后替换为新的副本，可以在任何 goroutine 中调用，返回的配置不能修改；
+// 上面的变量和 Server 是启动时的值，重新加载时不会修改
+func Current() *Config {
+	return current.Load().(*Config)
+}
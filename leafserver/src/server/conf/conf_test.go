@@ -0,0 +1,26 @@
+package conf
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestTestArgs(t *testing.T) {
+	args, test := testArgs([]string{"-test.paniconexit0", "-MaxConnNum=5", "-test.timeout=10m0s"})
+	if !test || !reflect.DeepEqual(args, []string{"-MaxConnNum=5"}) {
+		t.Errorf("got %v %v", args, test)
+	}
+}
+
+// 导入 conf 的测试程序不会因为 -test.* 参数和缺少配置文件退出
+func TestLoadInTest(t *testing.T) {
+	if !inTest || Current() == nil || Current().MaxConnNum != 100 {
+		t.Fatalf("got inTest %v, %+v", inTest, Current())
+	}
+	var b strings.Builder
+	Print(&b)
+	if !strings.Contains(b.String(), "MaxConnNum = 100\t# default") {
+		t.Errorf("got %v", b.String())
+	}
+}
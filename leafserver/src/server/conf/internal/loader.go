@@ -0,0 +1,625 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// 分层加载配置：默认值、配置文件、profile 文件、环境变量、命令行参数，后面的覆盖前面的
+//
+// profile 由 -profile 或环境变量 <EnvPrefix>PROFILE 指定，例如配置文件为 server.json，
+// profile 为 dev 时读取同一目录下的 server.dev.json
+type Loader struct {
+	File      string // 默认配置文件，可以由 -conf 或环境变量 <EnvPrefix>CONF 指定
+	EnvPrefix string
+	Getenv    func(key string) (string, bool)
+	Validate  func(v Values) []error // 全部层应用后检查配置
+
+	settings []*Setting
+	byName   map[string]*Setting
+}
+
+type Setting struct {
+	Name   string
+	Usage  string
+	Live   bool // 可以在运行时重新加载
+	Secret bool // 输出时隐藏
+	index  int
+	value  reflect.Value // 注册的变量，只在 Load 时写入
+	def    reflect.Value
+	cur    reflect.Value // 当前生效的值，重新加载后可能与 value 不同
+	source string
+}
+
+// 一次加载得到的全部配置项的值，与 settings 一一对应
+type Values struct {
+	settings []*Setting
+	values   []reflect.Value
+}
+
+// 把值复制到 ptr 指向的结构体中与配置项同名的字段，字段没有对应的配置项时 panic
+func (v Values) Decode(ptr interface{}) {
+	d := reflect.ValueOf(ptr).Elem()
+	t := d.Type()
+	for i := 0; i < t.NumField(); i++ {
+		found := false
+		for j, s := range v.settings {
+			if s.Name == t.Field(i).Name {
+				d.Field(i).Set(v.values[j])
+				found = true
+				break
+			}
+		}
+		if !found {
+			panic("conf: no setting " + t.Field(i).Name)
+		}
+	}
+}
+
+// 不能作为配置项名的参数
+var reserved = map[string]bool{"conf": true, "profile": true, "printconf": true, "h": true, "help": true}
+
+// 注册配置项，ptr 指向的当前值作为默认值
+func (l *Loader) Register(name string, ptr interface{}, usage string) *Setting {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		panic("conf: " + name + " must be a non-nil pointer")
+	}
+	if l.byName == nil {
+		l.byName = make(map[string]*Setting)
+	}
+	if _, ok := l.byName[name]; ok || reserved[name] {
+		panic("conf: duplicate setting " + name)
+	}
+	s := &Setting{Name: name, Usage: usage, index: len(l.settings), value: v.Elem()}
+	s.def = clone(s.value)
+	s.cur = s.def
+	l.settings = append(l.settings, s)
+	l.byName[name] = s
+	return s
+}
+
+// 注册结构体的全部导出字段，配置项名为字段名，
+// 标签 conf:"live" 表示可以在运行时重新加载，conf:"secret" 表示输出时隐藏
+func (l *Loader) RegisterStruct(ptr interface{}) {
+	v := reflect.ValueOf(ptr).Elem()
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		s := l.Register(f.Name, v.Field(i).Addr().Interface(), f.Tag.Get("usage"))
+		for _, opt := range strings.Split(f.Tag.Get("conf"), ",") {
+			switch opt {
+			case "live":
+				s.Live = true
+			case "secret":
+				s.Secret = true
+			case "":
+			default:
+				panic("conf: unknown option " + opt + " of " + f.Name)
+			}
+		}
+	}
+}
+
+func (l *Loader) Settings() []*Setting {
+	return l.settings
+}
+
+// 环境变量名，例如 TCPAddr 为 LEAF_TCP_ADDR
+func (l *Loader) EnvName(name string) string {
+	return l.EnvPrefix + envName(name)
+}
+
+func envName(name string) string {
+	r := []rune(name)
+	var b strings.Builder
+	for i, c := range r {
+		if i > 0 && unicode.IsUpper(c) &&
+			(unicode.IsLower(r[i-1]) || i+1 < len(r) && unicode.IsLower(r[i+1])) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(c))
+	}
+	return b.String()
+}
+
+func (l *Loader) getenv(key string) (string, bool) {
+	if l.Getenv != nil {
+		return l.Getenv(key)
+	}
+	return os.LookupEnv(key)
+}
+
+// 按顺序应用每一层，检查后写入注册的变量，所有错误一起返回；
+// 只有参数和配置文件本身有问题时不写入，其余错误也写入，用于输出配置后退出
+func (l *Loader) Load(args []string) error {
+	v, sources, err := l.stage(args)
+	if v.values == nil {
+		return err
+	}
+	errs, _ := err.(ErrorList)
+	errs = append(errs, l.validate(v)...)
+	for i, s := range l.settings {
+		s.value.Set(v.values[i])
+		s.cur = v.values[i]
+		s.source = sources[i]
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// 重新加载，出错时保留原来的配置；不写入注册的变量，可以在运行时修改的配置项生效后由 Values 取得，
+// 其余修改过的配置项保留原值，在 restart 中返回
+func (l *Loader) Reload(args []string) (applied []string, restart []string, err error) {
+	v, sources, err := l.stage(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	// 新的配置和生效的配置（不能修改的配置项为原值）都要通过检查
+	cur := Values{l.settings, make([]reflect.Value, len(l.settings))}
+	for i, s := range l.settings {
+		cur.values[i] = s.cur
+		if s.Live {
+			cur.values[i] = v.values[i]
+		}
+	}
+	errs := l.validate(v)
+	if len(errs) == 0 {
+		errs = l.validate(cur)
+	}
+	if len(errs) > 0 {
+		return nil, nil, ErrorList(errs)
+	}
+	for i, s := range l.settings {
+		if reflect.DeepEqual(s.cur.Interface(), v.values[i].Interface()) {
+			s.source = sources[i]
+			continue
+		}
+		if s.Live {
+			applied = append(applied, s.Name)
+			s.cur = v.values[i]
+			s.source = sources[i]
+		} else {
+			restart = append(restart, s.Name)
+		}
+	}
+	return applied, restart, nil
+}
+
+// 当前生效的值
+func (l *Loader) Values() Values {
+	v := Values{l.settings, make([]reflect.Value, len(l.settings))}
+	for i, s := range l.settings {
+		v.values[i] = s.cur
+	}
+	return v
+}
+
+func (l *Loader) validate(v Values) []error {
+	if l.Validate == nil {
+		return nil
+	}
+	return l.Validate(v)
+}
+
+// 从默认值开始按顺序应用每一层得到新的值，不修改注册的变量；
+// 参数和配置文件无法读取时 values 为空，其余错误与 values 一起返回
+func (l *Loader) stage(args []string) (Values, []string, error) {
+	flags, err := l.flags(args)
+	if err != nil {
+		return Values{}, nil, err
+	}
+
+	v := Values{l.settings, make([]reflect.Value, len(l.settings))}
+	sources := make([]string, len(l.settings))
+	for i, s := range l.settings {
+		v.values[i] = clone(s.def)
+		sources[i] = "default"
+	}
+	var errs ErrorList
+	for _, file := range l.ConfigFiles(args) {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return Values{}, nil, err
+		}
+		errs = append(errs, l.applyJSON(file, data, v, sources)...)
+	}
+	for i, s := range l.settings {
+		if e, ok := l.getenv(l.EnvName(s.Name)); ok {
+			if err := setString(v.values[i], e); err != nil {
+				errs = append(errs, fmt.Errorf("%v: %v", l.EnvName(s.Name), err))
+			}
+			sources[i] = "env " + l.EnvName(s.Name)
+		}
+	}
+	for _, f := range flags {
+		i := f.setting.index
+		if err := setString(v.values[i], f.value); err != nil {
+			errs = append(errs, fmt.Errorf("-%v: %v", f.setting.Name, err))
+		}
+		sources[i] = "flag -" + f.setting.Name
+	}
+	if len(errs) > 0 {
+		return v, sources, errs
+	}
+	return v, sources, nil
+}
+
+func clone(v reflect.Value) reflect.Value {
+	c := reflect.New(v.Type()).Elem()
+	c.Set(v)
+	return c
+}
+
+// 参数优先于环境变量 <EnvPrefix><NAME>
+func (l *Loader) option(args []string, name string, value string) string {
+	if v, ok := l.getenv(l.EnvPrefix + strings.ToUpper(name)); ok {
+		value = v
+	}
+	if v, ok := Flag(args, name); ok {
+		value = v
+	}
+	return value
+}
+
+// args 中 -name 的值，布尔参数的值为空
+func Flag(args []string, name string) (string, bool) {
+	value, found := "", false
+	for i := 0; i < len(args); i++ {
+		n, v, hasValue := splitFlag(args[i])
+		if n != name {
+			continue
+		}
+		if !hasValue && i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			i++
+			v = args[i]
+		}
+		value, found = v, true
+	}
+	return value, found
+}
+
+// 配置文件路径，相对路径在工作目录下不存在时在程序所在目录下查找
+func (l *Loader) ConfigFile(args []string) string {
+	return resolve(l.option(args, "conf", l.File))
+}
+
+// 当前的 profile
+func (l *Loader) Profile(args []string) string {
+	return l.option(args, "profile", "")
+}
+
+// 按顺序读取的配置文件，基础文件之后为 profile 文件
+func (l *Loader) ConfigFiles(args []string) []string {
+	file := l.ConfigFile(args)
+	if file == "" {
+		return nil
+	}
+	files := []string{file}
+	if profile := l.Profile(args); profile != "" {
+		ext := filepath.Ext(file)
+		files = append(files, strings.TrimSuffix(file, ext)+"."+profile+ext)
+	}
+	return files
+}
+
+func resolve(path string) string {
+	if path == "" || filepath.IsAbs(path) {
+		return path
+	}
+	if _, err := os.Stat(path); err == nil {
+		return path
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return path
+	}
+	if p := filepath.Join(filepath.Dir(exe), path); exists(p) {
+		return p
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// 配置文件的顶层键为配置项名
+func (l *Loader) applyJSON(file string, data []byte, v Values, sources []string) []error {
+	var m map[string]json.RawMessage
+	if err := json.Unmarshal(data, &m); err != nil {
+		return []error{fmt.Errorf("%v: %v", file, err)}
+	}
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, k := range keys {
+		s := l.byName[k]
+		if s == nil {
+			err := fmt.Errorf("%v: unknown key %q", file, k)
+			if name := l.suggest(k); name != "" {
+				err = fmt.Errorf("%v (did you mean %q?)", err, name)
+			}
+			errs = append(errs, err)
+			continue
+		}
+		if err := setJSON(v.values[s.index], m[k]); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v: %v", file, k, err))
+		}
+		sources[s.index] = file
+	}
+	return errs
+}
+
+// 与 key 最接近的配置项名，用于提示拼写错误
+func (l *Loader) suggest(key string) string {
+	best, bestDist := "", 3
+	for _, s := range l.settings {
+		if strings.EqualFold(s.Name, key) {
+			return s.Name
+		}
+		if d := distance(strings.ToLower(s.Name), strings.ToLower(key)); d < bestDist {
+			best, bestDist = s.Name, d
+		}
+	}
+	return best
+}
+
+// 编辑距离
+func distance(a string, b string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func min(a int, b int, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+type flagValue struct {
+	setting *Setting
+	value   string
+}
+
+// 从 args 中取出配置项参数，-name=value、-name value 和布尔值的 -name，不认识的参数返回错误
+func (l *Loader) flags(args []string) ([]flagValue, error) {
+	var flags []flagValue
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := splitFlag(args[i])
+		if name == "h" || name == "help" {
+			return nil, flag.ErrHelp
+		}
+		if name == "" {
+			continue
+		}
+		if reserved[name] {
+			if (name == "conf" || name == "profile") && !hasValue {
+				i++
+			}
+			continue
+		}
+		s := l.byName[name]
+		if s == nil {
+			err := fmt.Errorf("unknown flag -%v", name)
+			if name := l.suggest(name); name != "" {
+				err = fmt.Errorf("%v (did you mean -%v?)", err, name)
+			}
+			return nil, err
+		}
+		if !hasValue {
+			if s.value.Kind() == reflect.Bool {
+				value = "true"
+			} else if i+1 < len(args) {
+				i++
+				value = args[i]
+			} else {
+				return nil, fmt.Errorf("flag needs an argument: -%v", name)
+			}
+		}
+		flags = append(flags, flagValue{s, value})
+	}
+	return flags, nil
+}
+
+func splitFlag(arg string) (name string, value string, hasValue bool) {
+	if len(arg) < 2 || arg[0] != '-' {
+		return "", "", false
+	}
+	arg = strings.TrimPrefix(arg[1:], "-")
+	name, value, hasValue = strings.Cut(arg, "=")
+	return
+}
+
+// 输出当前生效的全部配置和来源，Secret 配置项不为空时隐藏
+func (l *Loader) Print(w io.Writer, args []string) {
+	if profile := l.Profile(args); profile != "" {
+		fmt.Fprintf(w, "# profile %v\n", profile)
+	}
+	for _, s := range l.settings {
+		value := format(s.cur)
+		if s.Secret && !s.cur.IsZero() {
+			value = "******"
+		}
+		fmt.Fprintf(w, "%v = %v\t# %v\n", s.Name, value, s.source)
+	}
+}
+
+// 输出全部配置项的说明
+func (l *Loader) Usage(w io.Writer) {
+	fmt.Fprintf(w, "  -conf string\n\tconfig file, env %vCONF (default %q)\n", l.EnvPrefix, l.File)
+	fmt.Fprintf(w, "  -profile string\n\tprofile merged over the config file, env %vPROFILE\n", l.EnvPrefix)
+	fmt.Fprintf(w, "  -printconf\n\tprint the effective config and exit\n")
+	for _, s := range l.settings {
+		fmt.Fprintf(w, "  -%v %v\n\t", s.Name, typeName(s.value.Type()))
+		if s.Usage != "" {
+			fmt.Fprintf(w, "%v, ", s.Usage)
+		}
+		fmt.Fprintf(w, "env %v (default %v)\n", l.EnvName(s.Name), format(s.def))
+	}
+}
+
+func typeName(t reflect.Type) string {
+	if t == durationType {
+		return "duration"
+	}
+	return t.String()
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+func format(v reflect.Value) string {
+	if v.Kind() == reflect.String {
+		return strconv.Quote(v.String())
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// 从环境变量和命令行的字符串设置值，切片用逗号分隔，map 为逗号分隔的 key=value
+func setString(v reflect.Value, s string) error {
+	if v.Type() == durationType {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Slice:
+		var parts []string
+		if s != "" {
+			parts = strings.Split(s, ",")
+		}
+		slice := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setString(slice.Index(i), strings.TrimSpace(p)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Map:
+		m := reflect.MakeMap(v.Type())
+		if s != "" {
+			for _, p := range strings.Split(s, ",") {
+				k, e, ok := strings.Cut(p, "=")
+				if !ok {
+					return fmt.Errorf("%q is not key=value", p)
+				}
+				key := reflect.New(v.Type().Key()).Elem()
+				if err := setString(key, strings.TrimSpace(k)); err != nil {
+					return err
+				}
+				elem := reflect.New(v.Type().Elem()).Elem()
+				if err := setString(elem, strings.TrimSpace(e)); err != nil {
+					return err
+				}
+				m.SetMapIndex(key, elem)
+			}
+		}
+		v.Set(m)
+	default:
+		return fmt.Errorf("unsupported type %v", v.Type())
+	}
+	return nil
+}
+
+// 时长可以写成 "10s" 或者秒数
+func setJSON(v reflect.Value, raw json.RawMessage) error {
+	if v.Type() == durationType {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return setString(v, s)
+		}
+		var sec float64
+		if err := json.Unmarshal(raw, &sec); err != nil {
+			return errors.New("duration must be a string like \"10s\" or a number of seconds")
+		}
+		v.SetInt(int64(sec * float64(time.Second)))
+		return nil
+	}
+	n := reflect.New(v.Type())
+	if err := json.Unmarshal(raw, n.Interface()); err != nil {
+		return err
+	}
+	v.Set(n.Elem())
+	return nil
+}
+
+// 多个错误，每行一个
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	s := make([]string, len(l))
+	for i, err := range l {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
@@ -0,0 +1,229 @@
+package internal
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testServer struct {
+	TCPAddr    string
+	MaxConnNum int
+	WSAddr     string
+}
+
+func newTestLoader(t *testing.T, content string, env map[string]string) (*Loader, *testServer, *time.Duration, func()) {
+	dir, err := ioutil.TempDir("", "conf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "server.json")
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	server := &testServer{MaxConnNum: 100}
+	timeout := 10 * time.Second
+	l := &Loader{File: file, EnvPrefix: "TEST_", Getenv: func(key string) (string, bool) {
+		v, ok := env[key]
+		return v, ok
+	}}
+	l.RegisterStruct(server)
+	l.Register("HTTPTimeout", &timeout, "")
+	return l, server, &timeout, func() { os.RemoveAll(dir) }
+}
+
+func TestLayers(t *testing.T) {
+	env := map[string]string{"TEST_MAX_CONN_NUM": "300", "TEST_WS_ADDR": "env:1"}
+	l, server, timeout, clean := newTestLoader(t,
+		`{"TCPAddr": "file:1", "MaxConnNum": 200, "HTTPTimeout": 3}`, env)
+	defer clean()
+
+	err := l.Load([]string{"-profile=", "-WSAddr", "flag:1", "extra"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := testServer{TCPAddr: "file:1", MaxConnNum: 300, WSAddr: "flag:1"}
+	if !reflect.DeepEqual(*server, want) {
+		t.Errorf("got %+v, want %+v", *server, want)
+	}
+	if *timeout != 3*time.Second {
+		t.Errorf("got timeout %v", *timeout)
+	}
+
+	//重新加载时先恢复默认值
+	delete(env, "TEST_MAX_CONN_NUM")
+	env["TEST_HTTP_TIMEOUT"] = "1m"
+	if err := l.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	want = testServer{TCPAddr: "file:1", MaxConnNum: 200, WSAddr: "env:1"}
+	if !reflect.DeepEqual(*server, want) || *timeout != time.Minute {
+		t.Errorf("got %+v %v", *server, *timeout)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	env := map[string]string{"TEST_MAX_CONN_NUM": "many"}
+	l, _, _, clean := newTestLoader(t, `{"TCPAddr": 1, "HTTPTimeout": "soon", "TCPAdr": ""}`, env)
+	defer clean()
+
+	err := l.Load([]string{"-MaxConnNum=x"})
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 5 {
+		t.Fatalf("got %v", err)
+	}
+	if want := `unknown key "TCPAdr" (did you mean "TCPAddr"?)`; !strings.HasSuffix(errs[2].Error(), want) {
+		t.Errorf("got %v, want %v", errs[2], want)
+	}
+	if err := l.Load([]string{"-conf", "missing.json"}); err == nil {
+		t.Error("expected missing file error")
+	}
+	err = l.Load([]string{"-MaxConnNm", "5"})
+	if want := "unknown flag -MaxConnNm (did you mean -MaxConnNum?)"; err == nil || err.Error() != want {
+		t.Errorf("got %v, want %v", err, want)
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	names := map[string]string{
+		"TCPAddr":            "TCP_ADDR",
+		"MaxConnNum":         "MAX_CONN_NUM",
+		"HTTPTimeout":        "HTTP_TIMEOUT",
+		"LogLevel":           "LOG_LEVEL",
+		"TimerDispatcherLen": "TIMER_DISPATCHER_LEN",
+		"ChanRPCLen":         "CHAN_RPC_LEN",
+	}
+	for name, want := range names {
+		if got := envName(name); got != want {
+			t.Errorf("%v: got %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestReload(t *testing.T) {
+	l, server, timeout, clean := newTestLoader(t, `{"TCPAddr": "a:1", "MaxConnNum": 200}`, nil)
+	defer clean()
+	l.settings[1].Live = true
+	if err := l.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+
+	file := l.ConfigFile(nil)
+	ioutil.WriteFile(file, []byte(`{"TCPAddr": "b:1", "MaxConnNum": 300, "HTTPTimeout": "10s"}`), 0644)
+	applied, restart, err := l.Reload(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(applied, []string{"MaxConnNum"}) || !reflect.DeepEqual(restart, []string{"TCPAddr"}) {
+		t.Errorf("got applied %v, restart %v", applied, restart)
+	}
+	//注册的变量保持启动时的值，生效的值由 Values 取得
+	if server.TCPAddr != "a:1" || server.MaxConnNum != 200 || *timeout != 10*time.Second {
+		t.Errorf("got %+v %v", *server, *timeout)
+	}
+	var live testServer
+	l.Values().Decode(&live)
+	if live.TCPAddr != "a:1" || live.MaxConnNum != 300 {
+		t.Errorf("got live %+v", live)
+	}
+
+	//出错时保留原来的配置
+	ioutil.WriteFile(file, []byte(`{"MaxConnNum": "x"}`), 0644)
+	if _, _, err := l.Reload(nil); err == nil {
+		t.Fatal("expected error")
+	}
+	l.Values().Decode(&live)
+	if live.MaxConnNum != 300 || live.TCPAddr != "a:1" {
+		t.Errorf("got %+v after failed reload", live)
+	}
+
+	//生效的配置也要通过检查，TCPAddr 不能在运行时修改
+	l.Validate = func(v Values) []error {
+		var s testServer
+		v.Decode(&s)
+		if s.TCPAddr == "a:1" && s.MaxConnNum > 400 {
+			return []error{errors.New("MaxConnNum too large for a:1")}
+		}
+		return nil
+	}
+	ioutil.WriteFile(file, []byte(`{"TCPAddr": "b:1", "MaxConnNum": 500}`), 0644)
+	if _, _, err := l.Reload(nil); err == nil {
+		t.Error("expected validate error")
+	}
+}
+
+func TestProfile(t *testing.T) {
+	env := map[string]string{"TEST_PROFILE": "dev"}
+	l, server, _, clean := newTestLoader(t, `{"TCPAddr": "base:1", "MaxConnNum": 200}`, env)
+	defer clean()
+	secret := "s3cret"
+	l.Register("Secret", &secret, "").Secret = true
+
+	file := l.ConfigFile(nil)
+	dev := strings.TrimSuffix(file, ".json") + ".dev.json"
+	ioutil.WriteFile(dev, []byte(`{"MaxConnNum": 50}`), 0644)
+	prod := strings.TrimSuffix(file, ".json") + ".prod.json"
+	ioutil.WriteFile(prod, []byte(`{"TCPAddr": "prod:1"}`), 0644)
+
+	if err := l.Load(nil); err != nil {
+		t.Fatal(err)
+	}
+	if server.TCPAddr != "base:1" || server.MaxConnNum != 50 {
+		t.Errorf("got %+v", *server)
+	}
+
+	//参数优先于环境变量
+	args := []string{"-profile", "prod"}
+	if err := l.Load(args); err != nil {
+		t.Fatal(err)
+	}
+	if server.TCPAddr != "prod:1" || server.MaxConnNum != 200 {
+		t.Errorf("got %+v", *server)
+	}
+
+	var b strings.Builder
+	l.Print(&b, args)
+	out := b.String()
+	for _, s := range []string{
+		"# profile prod\n",
+		`TCPAddr = "prod:1"` + "\t# " + prod + "\n",
+		"MaxConnNum = 200\t# " + file + "\n",
+		"WSAddr = \"\"\t# default\n",
+		`Secret = ******`,
+	} {
+		if !strings.Contains(out, s) {
+			t.Errorf("missing %q in\n%v", s, out)
+		}
+	}
+	if strings.Contains(out, secret) {
+		t.Errorf("secret printed:\n%v", out)
+	}
+
+	//profile 文件不存在
+	if err := l.Load([]string{"-profile=test"}); err == nil {
+		t.Error("expected error for missing profile file")
+	}
+}
+
+func TestSetString(t *testing.T) {
+	var rates map[string]float64
+	if err := setString(reflect.ValueOf(&rates).Elem(), "Hello=5, Auth=0.5"); err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]float64{"Hello": 5, "Auth": 0.5}; !reflect.DeepEqual(rates, want) {
+		t.Errorf("got %v, want %v", rates, want)
+	}
+	if err := setString(reflect.ValueOf(&rates).Elem(), "Hello"); err == nil {
+		t.Error("expected error for missing value")
+	}
+	var names []string
+	if err := setString(reflect.ValueOf(&names).Elem(), "a, b"); err != nil || !reflect.DeepEqual(names, []string{"a", "b"}) {
+		t.Errorf("got %v, %v", names, err)
+	}
+}
@@ -1,31 +1,130 @@
 package conf
 
 import (
-	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
 	"github.com/name5566/leaf/log"
-	"io/ioutil"
+
+	"GoLeafServer/leafserver/src/server/conf/internal"
 )
 
 var Server struct {
-	LogLevel    string
-	LogPath     string
-	WSAddr      string
-	CertFile    string
-	KeyFile     string
-	TCPAddr     string
-	MaxConnNum  int
-	ConsolePort int
-	ProfilePath string
+	LogLevel    string `usage:"debug, release, error or fatal"`
+	LogPath     string `usage:"log directory, empty for stdout"`
+	WSAddr      string `usage:"websocket listen address"`
+	CertFile    string `usage:"TLS certificate file for websocket"`
+	KeyFile     string `usage:"TLS key file for websocket"`
+	TCPAddr     string `usage:"tcp listen address"`
+	MaxConnNum  int    `usage:"max connections" conf:"live"`
+	ConsolePort int    `usage:"console port, 0 to disable"`
+	ProfilePath string `usage:"profile output directory"`
+
+	GamedataPath     string `usage:"gamedata directory"`
+	GamedataBundle   string `usage:"gamedata bundle file" conf:"live"`
+	GamedataChecksum string `usage:"sha256 of the gamedata bundle" conf:"live"`
+
+	AuthRequired  bool     `usage:"clients must authenticate before sending game messages"`
+	AuthSecret    string   `usage:"key that signs auth tokens" conf:"secret"`
+	HandshakeMsgs []string `usage:"messages allowed before authentication"`
+}
+
+var loader = &internal.Loader{File: "conf/server.json", EnvPrefix: "LEAF_"}
+
+// 启动参数，重新加载时命令行参数仍然优先
+var args, inTest = testArgs(os.Args[1:])
+
+// go test 生成的测试程序带有 -test.* 参数，这些参数不是配置项
+func testArgs(args []string) ([]string, bool) {
+	rest := make([]string, 0, len(args))
+	test := strings.HasSuffix(os.Args[0], ".test")
+	for _, arg := range args {
+		if strings.HasPrefix(arg, "-test.") {
+			test = true
+			continue
+		}
+		rest = append(rest, arg)
+	}
+	return rest, test
 }
 
 func init() {
-	data, err := ioutil.ReadFile("conf/" +
-		"server.json")
-	if err != nil {
-		log.Fatal("%v", err)
+	// 与 leaf 相同，没有配置时为 100
+	Server.MaxConnNum = 100
+	loader.RegisterStruct(&Server)
+	loader.Register("LogFlag", &LogFlag, "log flags of the standard log package")
+	loader.Register("PendingWriteNum", &PendingWriteNum, "pending messages per connection")
+	loader.Register("MaxMsgLen", &MaxMsgLen, "max message length in bytes")
+	loader.Register("HTTPTimeout", &HTTPTimeout, "websocket handshake timeout")
+	loader.Register("LenMsgLen", &LenMsgLen, "bytes of the tcp message length header")
+	loader.Register("LittleEndian", &LittleEndian, "little endian tcp message length header")
+	loader.Register("HandshakeTimeout", &HandshakeTimeout, "time to authenticate before the connection is closed").Live = true
+	loader.Register("IdleTimeout", &IdleTimeout, "close connections that send nothing for this long, 0 to disable").Live = true
+	loader.Register("HeartbeatInterval", &HeartbeatInterval, "ping idle connections at this interval, 0 to disable").Live = true
+	loader.Register("MaxConnPerIP", &MaxConnPerIP, "max connections from one ip, 0 for no limit").Live = true
+	loader.Register("MsgRate", &MsgRate, "messages per second per connection, 0 for no limit").Live = true
+	loader.Register("MsgBurst", &MsgBurst, "message burst per connection").Live = true
+	loader.Register("ByteRate", &ByteRate, "bytes per second per connection, 0 for no limit").Live = true
+	loader.Register("ByteBurst", &ByteBurst, "byte burst per connection").Live = true
+	loader.Register("MsgTypeRates", &MsgTypeRates, "messages per second per connection by message name, Name=rate").Live = true
+	loader.Register("RateLimitAction", &RateLimitAction, "drop, warn or disconnect when a rate limit is exceeded").Live = true
+	loader.Register("ResumeGrace", &ResumeGrace, "keep disconnected sessions for resume, 0 to disable").Live = true
+	loader.Register("ResumeBuffer", &ResumeBuffer, "unacknowledged messages kept for resume")
+	loader.Register("GoLen", &GoLen, "skeleton go channel length")
+	loader.Register("TimerDispatcherLen", &TimerDispatcherLen, "skeleton timer channel length")
+	loader.Register("AsynCallLen", &AsynCallLen, "skeleton async call channel length")
+	loader.Register("ChanRPCLen", &ChanRPCLen, "skeleton chanrpc channel length")
+
+	loader.Validate = func(v internal.Values) []error {
+		c := new(Config)
+		v.Decode(c)
+		return c.validate()
+	}
+
+	// 测试程序在包目录下运行，没有配置文件时使用默认值
+	if _, err := os.Stat(loader.ConfigFile(args)); inTest && err != nil {
+		loader.File = ""
+	}
+
+	// 各模块的 skeleton 在包初始化时创建，所以配置要在这里加载，有问题时在启动任何模块之前退出；
+	// 测试程序只记录问题，不退出
+	err := loader.Load(args)
+	if inTest && err != nil {
+		log.Error("conf: %v", err)
+		setCurrent()
+		return
+	}
+	if errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "Usage of %v:\n", os.Args[0])
+		loader.Usage(os.Stderr)
+		os.Exit(0)
+	}
+	if _, ok := internal.Flag(args, "printconf"); ok {
+		Print(os.Stdout)
+		if err == nil {
+			os.Exit(0)
+		}
+	}
+	if errs, ok := err.(internal.ErrorList); ok {
+		log.Fatal("conf: %v problems found\n%v", len(errs), errs)
 	}
-	err = json.Unmarshal(data, &Server)
 	if err != nil {
-		log.Fatal("%v", err)
+		log.Fatal("conf: %v", err)
 	}
+	setCurrent()
+}
+
+func setCurrent() {
+	c := new(Config)
+	loader.Values().Decode(c)
+	current.Store(c)
+}
+
+// 输出当前生效的配置和每一项的来源，密钥等配置项会被隐藏
+func Print(w io.Writer) {
+	loader.Print(w, args)
 }
@@ -0,0 +1,33 @@
+package conf
+
+import (
+	"github.com/name5566/leaf/chanrpc"
+)
+
+// 重新加载成功后通知订阅模块的 ChanRPC id，参数为已经生效的配置项名 []string
+const ReloadRPC = "ConfReloaded"
+
+var subscribers []*chanrpc.Server
+
+// 订阅重新加载通知，需要先在 server 上注册 ReloadRPC，在模块初始化时调用
+func Subscribe(server *chanrpc.Server) {
+	subscribers = append(subscribers, server)
+}
+
+// 重新读取配置，检查通过后应用可以在运行时修改的配置项，新的值由 Current 取得，
+// 其余修改过的配置项在 restart 中返回，需要重启生效
+// 只能在同一个模块的 goroutine 中调用
+func Reload() (applied []string, restart []string, err error) {
+	applied, restart, err = loader.Reload(args)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(applied) > 0 {
+		setCurrent()
+		// 订阅的模块可能就是调用者，通道满时同步发送会死锁
+		for _, server := range subscribers {
+			go server.Go(ReloadRPC, applied)
+		}
+	}
+	return applied, restart, nil
+}
@@ -0,0 +1,122 @@
+package conf
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// 配置检查，返回全部问题
+func (c *Config) validate() []error {
+	var errs []error
+	check := func(ok bool, format string, a ...interface{}) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, a...))
+		}
+	}
+
+	switch c.LogLevel {
+	case "debug", "release", "error", "fatal":
+	default:
+		errs = append(errs, fmt.Errorf("LogLevel: %q is not debug, release, error or fatal", c.LogLevel))
+	}
+
+	check(c.WSAddr != "" || c.TCPAddr != "", "WSAddr, TCPAddr: at least one listen address is required")
+	for _, addr := range []struct {
+		name  string
+		value string
+	}{{"WSAddr", c.WSAddr}, {"TCPAddr", c.TCPAddr}} {
+		if addr.value == "" {
+			continue
+		}
+		if err := checkAddr(addr.value); err != nil {
+			errs = append(errs, fmt.Errorf("%v: %v", addr.name, err))
+		}
+	}
+	check(c.ConsolePort >= 0 && c.ConsolePort <= 65535,
+		"ConsolePort: %v is out of range [0, 65535]", c.ConsolePort)
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		check(c.CertFile != "" && c.KeyFile != "", "CertFile, KeyFile: must be set together")
+		check(c.WSAddr != "", "CertFile, KeyFile: TLS requires WSAddr")
+		for _, f := range []struct {
+			name  string
+			value string
+		}{{"CertFile", c.CertFile}, {"KeyFile", c.KeyFile}} {
+			if f.value == "" {
+				continue
+			}
+			if _, err := os.Stat(f.value); err != nil {
+				errs = append(errs, fmt.Errorf("%v: %v", f.name, err))
+			}
+		}
+	}
+
+	check(c.MaxConnNum > 0, "MaxConnNum: %v must be positive", c.MaxConnNum)
+	check(c.PendingWriteNum > 0, "PendingWriteNum: %v must be positive", c.PendingWriteNum)
+	check(c.HTTPTimeout > 0, "HTTPTimeout: %v must be positive", c.HTTPTimeout)
+	if c.AuthRequired {
+		check(c.AuthSecret != "", "AuthSecret: required by AuthRequired")
+		check(c.HandshakeTimeout > 0, "HandshakeTimeout: %v must be positive", c.HandshakeTimeout)
+	}
+	check(c.IdleTimeout >= 0, "IdleTimeout: %v must not be negative", c.IdleTimeout)
+	check(c.HeartbeatInterval >= 0, "HeartbeatInterval: %v must not be negative", c.HeartbeatInterval)
+	if c.IdleTimeout > 0 && c.HeartbeatInterval > 0 {
+		check(c.HeartbeatInterval < c.IdleTimeout,
+			"HeartbeatInterval: %v must be less than IdleTimeout %v", c.HeartbeatInterval, c.IdleTimeout)
+	}
+	check(c.MaxConnPerIP >= 0, "MaxConnPerIP: %v must not be negative", c.MaxConnPerIP)
+	check(c.MsgRate >= 0, "MsgRate: %v must not be negative", c.MsgRate)
+	check(c.MsgRate == 0 || c.MsgBurst >= 1, "MsgBurst: %v must be at least 1", c.MsgBurst)
+	check(c.ByteRate >= 0, "ByteRate: %v must not be negative", c.ByteRate)
+	check(c.ByteRate == 0 || uint64(c.ByteBurst) >= uint64(c.MaxMsgLen),
+		"ByteBurst: %v must be at least MaxMsgLen %v", c.ByteBurst, c.MaxMsgLen)
+	for name, rate := range c.MsgTypeRates {
+		check(rate > 0, "MsgTypeRates: %v=%v must be positive", name, rate)
+	}
+	switch c.RateLimitAction {
+	case "drop", "warn", "disconnect":
+	default:
+		errs = append(errs, fmt.Errorf("RateLimitAction: %q is not drop, warn or disconnect", c.RateLimitAction))
+	}
+	check(c.ResumeGrace >= 0, "ResumeGrace: %v must not be negative", c.ResumeGrace)
+	if c.ResumeGrace > 0 {
+		// 恢复时一次写入全部消息，不能超过连接的写队列
+		check(c.ResumeBuffer > 0 && c.ResumeBuffer < c.PendingWriteNum,
+			"ResumeBuffer: %v is out of range [1, PendingWriteNum %v)", c.ResumeBuffer, c.PendingWriteNum)
+	}
+	switch c.LenMsgLen {
+	case 1, 2, 4:
+		max := uint64(1)<<(8*uint(c.LenMsgLen)) - 1
+		check(c.MaxMsgLen > 0 && uint64(c.MaxMsgLen) <= max,
+			"MaxMsgLen: %v is out of range [1, %v] for LenMsgLen %v", c.MaxMsgLen, max, c.LenMsgLen)
+	default:
+		errs = append(errs, fmt.Errorf("LenMsgLen: %v is not 1, 2 or 4", c.LenMsgLen))
+	}
+	for _, n := range []struct {
+		name  string
+		value int
+	}{
+		{"GoLen", c.GoLen},
+		{"TimerDispatcherLen", c.TimerDispatcherLen},
+		{"AsynCallLen", c.AsynCallLen},
+		{"ChanRPCLen", c.ChanRPCLen},
+	} {
+		check(n.value > 0 && n.value <= 1<<20, "%v: %v is out of range [1, %v]", n.name, n.value, 1<<20)
+	}
+	return errs
+}
+
+// host:port，host 可以为空，port 为 1 到 65535
+func checkAddr(addr string) error {
+	_, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return err
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil || n < 1 || n > 65535 {
+		return fmt.Errorf("invalid port %q in %q", port, addr)
+	}
+	return nil
+}
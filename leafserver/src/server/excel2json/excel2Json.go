@@ -0,0 +1,269 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"path/filepath"
+
+	Rf "GoLeafServer/leafserver/src/server/recordfile"
+)
+
+//匹配模式段，匹配的sheet不转换
+var RegexpPattern = "^#.*"
+//sheet命名规则，不为空时只转换匹配的sheet
+var SheetPattern = ""
+//生成的目录
+var JsonDir = "Json"
+//生成的Go代码目录
+var GoDir = "Go"
+//生成的recordfile格式txt目录
+var TxtDir = "Txt"
+//生成的客户端代码目录
+var CsDir = "Cs"
+var TsDir = "Ts"
+
+//表格行定义，下标从0开始
+const (
+	DescRow    = 0 //描述行
+	CommentRow = 1 //注释行
+	FieldRow   = 2 //字段名行
+	TypeRow    = 3 //字段类型行
+	DataRow    = 4 //数据起始行
+)
+
+//读取表格，每个sheet转换为一张表，sheet内容的错误以ErrorList返回，其他为读取失败
+func readExcel(filaname string) ([]*Table, error) {
+	sheets, err := Rf.ReadXLSXSheets(filaname)
+	if err != nil {
+		return nil, fmt.Errorf("The filename [%v] [%v]", filaname, err)
+	}
+
+	file := filepath.Base(filaname)
+	var tables []*Table
+	var errs ErrorList
+	for _, sheet := range sheets {
+		sheetName := sheet.Name
+		convert, err := convertSheet(sheetName)
+		if err != nil {
+			return nil, err
+		}
+		if !convert {
+			logf("跳过sheet [%v]", sheetName)
+			continue
+		}
+		table, err := readSheet(file, sheetName, sheet.Rows)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		tables = append(tables, table)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return tables, nil
+}
+
+//按RegexpPattern和SheetPattern判断sheet是否需要转换
+func convertSheet(sheetName string) (bool, error) {
+	skip, err := regexp.MatchString(RegexpPattern, sheetName)
+	if err != nil {
+		return false, fmt.Errorf("match string pattern[%v] string[%v]: %v", RegexpPattern, sheetName, err)
+	}
+	if skip {
+		return false, nil
+	}
+	if SheetPattern == "" {
+		return true, nil
+	}
+	match, err := regexp.MatchString(SheetPattern, sheetName)
+	if err != nil {
+		return false, fmt.Errorf("match string pattern[%v] string[%v]: %v", SheetPattern, sheetName, err)
+	}
+	return match, nil
+}
+
+//按字段名行和类型行解析sheet，所有单元格错误一起返回
+func readSheet(file string, sheet string, rows [][]string) (*Table, error) {
+	table := &Table{
+		Name:  sheet,
+		File:  file,
+		Sheet: sheet,
+	}
+	var errs ErrorList
+	cellErr := func(row, col int, err error) {
+		errs = append(errs, &CellError{File: file, Sheet: sheet, Row: row, Col: col, Err: err})
+	}
+
+	if len(rows) <= TypeRow {
+		return nil, fmt.Errorf("%v[%v]: missing field name or type row", file, sheet)
+	}
+	//字段名
+	names := map[string]bool{}
+	for k, colCell := range rows[FieldRow] {
+		colCell = strings.TrimSpace(colCell)
+		if colCell == "" {
+			continue
+		}
+		if names[colCell] {
+			cellErr(FieldRow, k, fmt.Errorf("duplicate field %q", colCell))
+			continue
+		}
+		names[colCell] = true
+
+		decl := ""
+		if k < len(rows[TypeRow]) {
+			decl = rows[TypeRow][k]
+		}
+		fieldType, err := parseFieldType(decl)
+		if err != nil {
+			cellErr(TypeRow, k, fmt.Errorf("field %v: %v", colCell, err))
+			continue
+		}
+		table.Columns = append(table.Columns, &Column{Name: colCell, Type: fieldType, Index: k})
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	for rowindex := DataRow; rowindex < len(rows); rowindex++ {
+		row := rows[rowindex]
+		if isEmptyRow(row) {
+			continue
+		}
+		values := make([]interface{}, len(table.Columns))
+		for i, col := range table.Columns {
+			colCell := ""
+			if col.Index < len(row) {
+				colCell = row[col.Index]
+			}
+			v, err := col.Type.Parse(colCell)
+			if err != nil {
+				cellErr(rowindex, col.Index, fmt.Errorf("field %v: %v", col.Name, err))
+				continue
+			}
+			values[i] = v
+		}
+		table.Rows = append(table.Rows, values)
+		table.Lines = append(table.Lines, rowindex)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if err := localize(table); err != nil {
+		return nil, err
+	}
+
+	return table, nil
+}
+
+func isEmptyRow(row []string) bool {
+	for _, colCell := range row {
+		if strings.TrimSpace(colCell) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+//输出json文件
+func writeJson(table *Table, name string) error {
+	buf := new(bytes.Buffer)
+	buf.WriteString("[\n")
+	for i, row := range table.Rows {
+		buf.WriteString("\t{\n")
+		for k, col := range table.Columns {
+			key, err := marshalValue(col.Name)
+			if err != nil {
+				return err
+			}
+			value, err := marshalValue(row[k])
+			if err != nil {
+				return fmt.Errorf("%v field %v: %v", table.Name, col.Name, err)
+			}
+			fmt.Fprintf(buf, "\t%s:%s", key, value)
+			if k+1 < len(table.Columns) {
+				buf.WriteString(",\n")
+			}
+		}
+		buf.WriteString("\n\t}")
+		if i+1 < len(table.Rows) {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+	}
+	buf.WriteString("]")
+
+	//创建json文件
+	return ioutil.WriteFile(name, buf.Bytes(), 0644)
+}
+
+func marshalValue(v interface{}) ([]byte, error) {
+	buf := new(bytes.Buffer)
+	enc := json.NewEncoder(buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+//输出一张表对应的全部文件，顺序与outputFiles一致
+func writeTable(table *Table) error {
+	files := outputFiles(table.Name)
+	if err := writeJson(table, files[0]); err != nil {
+		return err
+	}
+	if err := writeGo(table, files[1]); err != nil {
+		return err
+	}
+	if err := writeTxt(table, files[2]); err != nil {
+		return err
+	}
+	if err := writeClient(table, genCSharp, files[3]); err != nil {
+		return err
+	}
+	if err := writeClient(table, genTypeScript, files[4]); err != nil {
+		return err
+	}
+	if PackedDir != "" {
+		if err := writePacked(table, files[5]); err != nil {
+			return err
+		}
+	}
+	return writeTexts(table)
+}
+
+//输出目录，相对路径基于-out
+func outPath(dir string, name string) string {
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(*outDir, dir)
+	}
+	return filepath.Join(dir, name)
+}
+
+//创建全部输出目录，clean为true时先清空
+func createDirs(clean bool) error {
+	dirs := []string{JsonDir, GoDir, TxtDir, CsDir, TsDir, LangDir}
+	if PackedDir != "" {
+		dirs = append(dirs, PackedDir)
+	}
+	for _, dir := range dirs {
+		dir = outPath(dir, "")
+		if clean {
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+		}
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
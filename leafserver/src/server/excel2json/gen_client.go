@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+//生成的C#代码的命名空间
+var CsNamespace = "Config"
+
+func tsType(t *FieldType) string {
+	switch t.Kind {
+	case IntType, FloatType, RefType:
+		return "number"
+	case StringType, TextType:
+		return "string"
+	case BoolType:
+		return "boolean"
+	case ArrType:
+		return tsType(t.Elem) + "[]"
+	case MapType:
+		return "{ [key: string]: any }"
+	case EnumType:
+		values := make([]string, len(t.Enum))
+		for i, v := range t.Enum {
+			values[i] = strconv.Quote(v)
+		}
+		return strings.Join(values, " | ")
+	}
+	return "any"
+}
+
+//单元格中的整数按int64解析，C#使用long
+func csType(t *FieldType) string {
+	switch t.Kind {
+	case IntType, RefType:
+		return "long"
+	case FloatType:
+		return "double"
+	case StringType, EnumType, TextType:
+		return "string"
+	case BoolType:
+		return "bool"
+	case ArrType:
+		return "List<" + csType(t.Elem) + ">"
+	case MapType:
+		return "Dictionary<string, object>"
+	}
+	return "object"
+}
+
+//生成表对应的TypeScript定义，字段名与json一致
+func genTypeScript(table *Table) ([]byte, error) {
+	typeName, err := goIdent(table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("table %v: %v", table.Name, err)
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by excel2json from %v[%v]. DO NOT EDIT.\n\n", table.File, table.Sheet)
+	fmt.Fprintf(buf, "export interface %v {\n", typeName)
+	for _, col := range table.Columns {
+		fmt.Fprintf(buf, "\t%v: %v;\n", col.Name, tsType(col.Type))
+	}
+	fmt.Fprintf(buf, "}\n")
+	return buf.Bytes(), nil
+}
+
+//生成表对应的C#定义，字段名与json一致
+func genCSharp(table *Table) ([]byte, error) {
+	typeName, err := goIdent(table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("table %v: %v", table.Name, err)
+	}
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by excel2json from %v[%v]. DO NOT EDIT.\n\n", table.File, table.Sheet)
+	fmt.Fprintf(buf, "using System;\nusing System.Collections.Generic;\n\n")
+	fmt.Fprintf(buf, "namespace %v\n{\n", CsNamespace)
+	fmt.Fprintf(buf, "    [Serializable]\n    public class %v\n    {\n", typeName)
+	for _, col := range table.Columns {
+		fmt.Fprintf(buf, "        public %v %v;\n", csType(col.Type), col.Name)
+	}
+	fmt.Fprintf(buf, "    }\n}\n")
+	return buf.Bytes(), nil
+}
+
+//输出客户端代码
+func writeClient(table *Table, gen func(*Table) ([]byte, error), name string) error {
+	src, err := gen(table)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, src, 0644)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"go/format"
+	"os"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//生成的Go代码所在的包，需要包含readRf函数
+var GoPackage = "gamedata"
+
+//表名、字段名转换为导出的Go标识符
+func goIdent(name string) (string, error) {
+	if name == "" {
+		return "", fmt.Errorf("empty name")
+	}
+	for i, r := range name {
+		if r == '_' || unicode.IsLetter(r) || (i > 0 && unicode.IsDigit(r)) {
+			continue
+		}
+		return "", fmt.Errorf("%q is not a valid Go identifier", name)
+	}
+	r := []rune(name)
+	if r[0] == '_' {
+		return "", fmt.Errorf("%q is not a valid exported Go identifier", name)
+	}
+	r[0] = unicode.ToUpper(r[0])
+	return string(r), nil
+}
+
+func goType(t *FieldType) string {
+	switch t.Kind {
+	case IntType, RefType:
+		return "int"
+	case FloatType:
+		return "float64"
+	case StringType, EnumType, TextType:
+		return "string"
+	case BoolType:
+		return "bool"
+	case ArrType:
+		return "[]" + goType(t.Elem)
+	case MapType:
+		return "map[string]interface{}"
+	}
+	return "interface{}"
+}
+
+//第一列为主键，数组和map不能作为主键
+func keyColumn(table *Table) *Column {
+	if len(table.Columns) == 0 {
+		return nil
+	}
+	switch col := table.Columns[0]; col.Type.Kind {
+	case ArrType, MapType:
+		return nil
+	default:
+		return col
+	}
+}
+
+//生成表对应的结构体和加载函数
+func genGo(table *Table) ([]byte, error) {
+	typeName, err := goIdent(table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("table %v: %v", table.Name, err)
+	}
+	rfName := "rf" + typeName
+	key := keyColumn(table)
+
+	buf := new(bytes.Buffer)
+	fmt.Fprintf(buf, "// Code generated by excel2json from %v[%v]. DO NOT EDIT.\n\n", table.File, table.Sheet)
+	fmt.Fprintf(buf, "package %v\n\n", GoPackage)
+	fmt.Fprintf(buf, "type %v struct {\n", typeName)
+	for _, col := range table.Columns {
+		fieldName, err := goIdent(col.Name)
+		if err != nil {
+			return nil, fmt.Errorf("table %v field %v: %v", table.Name, col.Name, err)
+		}
+		if col == key {
+			fmt.Fprintf(buf, "\t%v %v `rf:\"index\"`\n", fieldName, goType(col.Type))
+		} else {
+			fmt.Fprintf(buf, "\t%v %v\n", fieldName, goType(col.Type))
+		}
+	}
+	fmt.Fprintf(buf, "}\n\n")
+
+	fmt.Fprintf(buf, "var %v = readRf(%v{})\n\n", rfName, typeName)
+	if key != nil {
+		keyName, _ := goIdent(key.Name)
+		fmt.Fprintf(buf, "//按%v查找，不存在时返回nil\n", keyName)
+		fmt.Fprintf(buf, "func Get%v(key %v) *%v {\n", typeName, goType(key.Type), typeName)
+		fmt.Fprintf(buf, "\tr, _ := %v.Index(key).(*%v)\n", rfName, typeName)
+		fmt.Fprintf(buf, "\treturn r\n}\n\n")
+	}
+	fmt.Fprintf(buf, "func %vRecord(i int) *%v {\n", typeName, typeName)
+	fmt.Fprintf(buf, "\treturn %v.Record(i).(*%v)\n}\n\n", rfName, typeName)
+	fmt.Fprintf(buf, "func Num%v() int {\n", typeName)
+	fmt.Fprintf(buf, "\treturn %v.NumRecord()\n}\n", rfName)
+
+	return format.Source(buf.Bytes())
+}
+
+//输出Go代码
+func writeGo(table *Table, name string) error {
+	src, err := genGo(table)
+	if err != nil {
+		return err
+	}
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(src)
+	return err
+}
+
+//输出recordfile格式的txt文件，第一行为字段名，数组和map按json格式输出
+func writeTxt(table *Table, name string) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	w := csv.NewWriter(f)
+	w.Comma = '\t'
+	line := make([]string, len(table.Columns))
+	for i, col := range table.Columns {
+		line[i] = col.Name
+	}
+	w.Write(line)
+	for _, row := range table.Rows {
+		for i, v := range row {
+			s, err := txtValue(v)
+			if err != nil {
+				return fmt.Errorf("%v field %v: %v", table.Name, table.Columns[i].Name, err)
+			}
+			line[i] = s
+		}
+		w.Write(line)
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func txtValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	}
+	b, err := marshalValue(v)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
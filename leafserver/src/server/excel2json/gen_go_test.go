@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenGo(t *testing.T) {
+	rows := [][]string{
+		{"编号", "名字", "标签"},
+		{"#"},
+		{"id", "Name", "Tags"},
+		{"int", "string", "[]int"},
+		{"1", "sword", "1,2"},
+	}
+	table, err := readSheet("Item.xlsx", "Item", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := genGo(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"type Item struct",
+		"Id   int `rf:\"index\"`",
+		"Tags []int",
+		"var rfItem = readRf(Item{})",
+		"func GetItem(key int) *Item",
+	} {
+		if !strings.Contains(string(src), s) {
+			t.Errorf("missing %q in:\n%s", s, src)
+		}
+	}
+}
+
+func TestGenClient(t *testing.T) {
+	rows := [][]string{
+		{"编号", "品质", "标签"},
+		{"#"},
+		{"Id", "Quality", "Tags"},
+		{"int", "enum:White|Blue", "[]string"},
+	}
+	table, err := readSheet("Item.xlsx", "Item", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ts, _ := genTypeScript(table)
+	if !strings.Contains(string(ts), "\tQuality: \"White\" | \"Blue\";\n\tTags: string[];") {
+		t.Errorf("unexpected TypeScript:\n%s", ts)
+	}
+	cs, _ := genCSharp(table)
+	if !strings.Contains(string(cs), "public long Id;") || !strings.Contains(string(cs), "public List<string> Tags;") {
+		t.Errorf("unexpected C#:\n%s", cs)
+	}
+}
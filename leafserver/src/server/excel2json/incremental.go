@@ -0,0 +1,222 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+//表格文件的sha1
+func hashFile(name string) (string, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	sum := sha1.Sum(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+//一张表生成的全部文件，服务器读取的txt和bin与生成的Go类型同名
+func outputFiles(name string) []string {
+	typeName, err := goIdent(name)
+	if err != nil {
+		typeName = name
+	}
+	files := []string{
+		outPath(JsonDir, name+".json"),
+		outPath(GoDir, strings.ToLower(name)+".go"),
+		outPath(TxtDir, typeName+".txt"),
+		outPath(CsDir, name+".cs"),
+		outPath(TsDir, name+".ts"),
+	}
+	if PackedDir != "" {
+		files = append(files, outPath(PackedDir, typeName+".bin"))
+	}
+	return files
+}
+
+//删除一张表生成的全部文件
+func removeOutputs(name string) error {
+	texts, err := textFiles(name)
+	if err != nil {
+		return err
+	}
+	for _, f := range append(outputFiles(name), texts...) {
+		if err := os.Remove(f); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+//从上次生成的json重新构造表，用于未修改的表格参与校验以及计算差异
+func loadTable(entry *ManifestEntry) (*Table, error) {
+	table := &Table{
+		Name:  entry.Name,
+		File:  entry.File,
+		Sheet: entry.Sheet,
+		Lines: entry.Lines,
+	}
+	for _, c := range entry.Columns {
+		fieldType, err := parseFieldType(c.Type)
+		if err != nil {
+			return nil, fmt.Errorf("%v field %v: %v", entry.Name, c.Name, err)
+		}
+		table.Columns = append(table.Columns, &Column{Name: c.Name, Type: fieldType, Index: c.Index})
+	}
+
+	data, err := ioutil.ReadFile(outPath(JsonDir, entry.Output))
+	if err != nil {
+		return nil, err
+	}
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &rows); err != nil {
+		return nil, fmt.Errorf("%v: %v", entry.Output, err)
+	}
+	if len(table.Lines) != len(rows) {
+		return nil, fmt.Errorf("%v: row count mismatch", entry.Output)
+	}
+	for i, row := range rows {
+		values := make([]interface{}, len(table.Columns))
+		for k, col := range table.Columns {
+			v, err := decodeValue(col.Type, row[col.Name])
+			if err != nil {
+				return nil, fmt.Errorf("%v row %v field %v: %v", entry.Output, i, col.Name, err)
+			}
+			values[k] = v
+		}
+		table.Rows = append(table.Rows, values)
+	}
+	return table, nil
+}
+
+//json解码为与FieldType.Parse相同类型的值
+func decodeValue(t *FieldType, raw json.RawMessage) (interface{}, error) {
+	if raw == nil {
+		return nil, fmt.Errorf("missing value")
+	}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	switch t.Kind {
+	case IntType, RefType:
+		var n json.Number
+		if err := dec.Decode(&n); err != nil {
+			return nil, err
+		}
+		return n.Int64()
+	case FloatType:
+		var n json.Number
+		if err := dec.Decode(&n); err != nil {
+			return nil, err
+		}
+		return n.Float64()
+	case StringType, EnumType, TextType:
+		var s string
+		err := dec.Decode(&s)
+		return s, err
+	case BoolType:
+		var b bool
+		err := dec.Decode(&b)
+		return b, err
+	case ArrType:
+		var arr []json.RawMessage
+		if err := dec.Decode(&arr); err != nil {
+			return nil, err
+		}
+		values := make([]interface{}, 0, len(arr))
+		for _, elem := range arr {
+			v, err := decodeValue(t.Elem, elem)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, v)
+		}
+		return values, nil
+	case MapType:
+		return raw, nil
+	}
+	return nil, fmt.Errorf("unknown field type %q", t.Decl)
+}
+
+//一张表的变化
+type TableDiff struct {
+	Table   string `json:"table"`
+	Status  string `json:"status"` //added removed changed
+	Added   int    `json:"added"`
+	Removed int    `json:"removed"`
+	Changed int    `json:"changed"`
+}
+
+func (d *TableDiff) String() string {
+	return fmt.Sprintf("%v %v: +%v -%v ~%v", d.Status, d.Table, d.Added, d.Removed, d.Changed)
+}
+
+//按主键比较新旧两张表的数据，没有主键时按行比较，old为nil表示新增的表
+func diffTable(old *Table, table *Table) (*TableDiff, error) {
+	diff := &TableDiff{Table: table.Name, Status: "changed"}
+	if old == nil {
+		diff.Status = "added"
+		diff.Added = len(table.Rows)
+		return diff, nil
+	}
+
+	oldRows, err := rowsByKey(old)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := rowsByKey(table)
+	if err != nil {
+		return nil, err
+	}
+	for key, row := range rows {
+		oldRow, ok := oldRows[key]
+		if !ok {
+			diff.Added++
+		} else if oldRow != row {
+			diff.Changed++
+		}
+	}
+	for key := range oldRows {
+		if _, ok := rows[key]; !ok {
+			diff.Removed++
+		}
+	}
+	//字段变化时所有行都视为修改
+	if !sameColumns(old, table) {
+		diff.Changed = len(table.Rows) - diff.Added
+	}
+	return diff, nil
+}
+
+func rowsByKey(table *Table) (map[string]string, error) {
+	rows := make(map[string]string, len(table.Rows))
+	for i, row := range table.Rows {
+		data, err := marshalValue(row)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", table.Name, err)
+		}
+		key := fmt.Sprint("#", i)
+		if keyColumn(table) != nil {
+			key = fmt.Sprint(row[0])
+		}
+		rows[key] = string(data)
+	}
+	return rows, nil
+}
+
+func sameColumns(a *Table, b *Table) bool {
+	if len(a.Columns) != len(b.Columns) {
+		return false
+	}
+	for i := range a.Columns {
+		if a.Columns[i].Name != b.Columns[i].Name || a.Columns[i].Type.Decl != b.Columns[i].Type.Decl {
+			return false
+		}
+	}
+	return true
+}
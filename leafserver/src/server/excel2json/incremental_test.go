@@ -0,0 +1,133 @@
+package main
+
+import (
+	Rf "GoLeafServer/leafserver/src/server/recordfile"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func testTable(t *testing.T, rows ...[]string) *Table {
+	table, err := readSheet("Item.xlsx", "Item", append([][]string{
+		{"编号", "名字", "标签", "属性"},
+		{"#"},
+		{"Id", "Name", "Tags", "Attr"},
+		{"int", "string", "[]float", "map"},
+	}, rows...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return table
+}
+
+func TestLoadTable(t *testing.T) {
+	dir, err := ioutil.TempDir("", "excel2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { *outDir = old }(*outDir)
+	*outDir = dir
+	if err := createDirs(false); err != nil {
+		t.Fatal(err)
+	}
+
+	table := testTable(t, []string{"1", "sword", "1.5,2", `{"atk":1}`}, []string{"3", "", "", ""})
+	if err := writeTable(table); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := loadTable(newManifestEntry(table, ""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded.Rows, table.Rows) || !reflect.DeepEqual(loaded.Lines, table.Lines) {
+		t.Errorf("got %v, want %v", loaded.Rows, table.Rows)
+	}
+
+	if err := removeOutputs(table.Name); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadTables([]*ManifestEntry{newManifestEntry(table, "")}); err == nil {
+		t.Error("expected missing output error")
+	}
+}
+
+func TestManifestUnchanged(t *testing.T) {
+	table := testTable(t, []string{"1", "sword", "", ""})
+	m := Manifest{newManifestEntry(table, "h1")}
+	options := optionsFingerprint()
+	if entries := m.Unchanged("Item.xlsx", "h1", options); len(entries) != 1 {
+		t.Errorf("got %v entries, want 1", len(entries))
+	}
+	if entries := m.Unchanged("Item.xlsx", "h2", options); entries != nil {
+		t.Error("changed file reported unchanged")
+	}
+
+	//修改影响输出的参数后需要重新转换
+	defer func(old string) { GoPackage = old }(GoPackage)
+	GoPackage = "config"
+	if entries := m.Unchanged("Item.xlsx", "h1", optionsFingerprint()); entries != nil {
+		t.Error("changed options reported unchanged")
+	}
+}
+
+func TestOutputFiles(t *testing.T) {
+	files := outputFiles("item")
+	if got := filepath.Base(files[2]); got != "Item.txt" {
+		t.Errorf("got %v, want Item.txt", got)
+	}
+}
+
+func TestDiffTable(t *testing.T) {
+	old := testTable(t,
+		[]string{"1", "sword", "", ""},
+		[]string{"2", "shield", "", ""},
+		[]string{"3", "bow", "", ""})
+	table := testTable(t,
+		[]string{"1", "sword", "", ""},
+		[]string{"2", "big shield", "", ""},
+		[]string{"4", "arrow", "", ""})
+
+	diff, err := diffTable(old, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := TableDiff{Table: "Item", Status: "changed", Added: 1, Removed: 1, Changed: 1}
+	if *diff != want {
+		t.Errorf("got %v, want %v", diff, &want)
+	}
+}
+
+func TestWritePacked(t *testing.T) {
+	dir, err := ioutil.TempDir("", "excel2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table := testTable(t, []string{"1", "sword", "1.5,2", `{"atk":1}`})
+	name := filepath.Join(dir, "Item.bin")
+	if err := writePacked(table, name); err != nil {
+		t.Fatal(err)
+	}
+
+	type Item struct {
+		Id   int
+		Name string
+		Tags []float64
+		Attr map[string]int
+	}
+	rf, err := Rf.New(Item{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.ReadPacked(name); err != nil {
+		t.Fatal(err)
+	}
+	want := &Item{Id: 1, Name: "sword", Tags: []float64{1.5, 2}, Attr: map[string]int{"atk": 1}}
+	if got := rf.Record(0); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
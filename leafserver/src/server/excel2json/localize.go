@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha1"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//text列中文本的语言
+var DefaultLocale = "zh"
+
+//需要检查翻译的语言，逗号分隔，为空时使用表中出现的翻译列
+var Locales = ""
+
+//多语言文本目录，每种语言一个子目录，每张表一个json文件
+var LangDir = "Lang"
+
+//提取text列的文本，翻译列为 字段名@语言，如Name@en。
+//text列的值替换为多语言key，翻译列不输出
+func localize(table *Table) error {
+	var errs ErrorList
+	var keep []int
+	trans := make(map[*Column]map[string]int)
+	found := make(map[string]bool)
+	for k, col := range table.Columns {
+		i := strings.Index(col.Name, "@")
+		if i < 0 {
+			keep = append(keep, k)
+			continue
+		}
+		base, locale := col.Name[:i], col.Name[i+1:]
+		text := table.Column(base)
+		switch {
+		case text == nil || text.Type.Kind != TextType:
+			errs = append(errs, table.cellError(FieldRow, col, fmt.Errorf("%v is not a text field", base)))
+		case col.Type.Kind != TextType:
+			errs = append(errs, table.cellError(TypeRow, col, fmt.Errorf("translation must be text")))
+		case locale == "" || locale == DefaultLocale:
+			errs = append(errs, table.cellError(FieldRow, col, fmt.Errorf("invalid locale %q", locale)))
+		default:
+			if trans[text] == nil {
+				trans[text] = make(map[string]int)
+			}
+			trans[text][locale] = k
+			found[locale] = true
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+
+	locales := checkLocales(found)
+
+	for k, col := range table.Columns {
+		if col.Type.Kind != TextType || strings.Contains(col.Name, "@") {
+			continue
+		}
+		if table.Texts == nil {
+			table.Texts = map[string]map[string]string{DefaultLocale: {}}
+			table.Missing = make(map[string][]string)
+			for _, locale := range locales {
+				table.Texts[locale] = make(map[string]string)
+			}
+		}
+		for _, row := range table.Rows {
+			text := row[k].(string)
+			if text == "" {
+				continue
+			}
+			key := textKey(table, col, row, text)
+			table.Texts[DefaultLocale][key] = text
+			for _, locale := range locales {
+				if j, ok := trans[col][locale]; ok && row[j].(string) != "" {
+					table.Texts[locale][key] = row[j].(string)
+				} else {
+					table.Missing[locale] = append(table.Missing[locale], key)
+				}
+			}
+			row[k] = key
+		}
+	}
+
+	//去掉翻译列
+	columns := make([]*Column, len(keep))
+	for i, k := range keep {
+		columns[i] = table.Columns[k]
+	}
+	for n, row := range table.Rows {
+		values := make([]interface{}, len(keep))
+		for i, k := range keep {
+			values[i] = row[k]
+		}
+		table.Rows[n] = values
+	}
+	table.Columns = columns
+	return nil
+}
+
+//需要检查翻译的语言，Locales为空时使用found中的语言
+func checkLocales(found map[string]bool) []string {
+	var locales []string
+	for _, locale := range strings.Split(Locales, ",") {
+		if locale = strings.TrimSpace(locale); locale != "" && locale != DefaultLocale {
+			locales = append(locales, locale)
+		}
+	}
+	if len(locales) == 0 {
+		for locale := range found {
+			if locale != DefaultLocale {
+				locales = append(locales, locale)
+			}
+		}
+		sort.Strings(locales)
+	}
+	return locales
+}
+
+//未修改的表按上次输出的多语言文本计算缺少的翻译，语言目录下没有文件时全部缺少
+func loadMissing(table *Table) error {
+	files, err := textFiles(table.Name)
+	if err != nil {
+		return err
+	}
+	texts := make(map[string]map[string]string)
+	found := make(map[string]bool)
+	for _, f := range files {
+		data, err := ioutil.ReadFile(f)
+		if err != nil {
+			return err
+		}
+		var m map[string]string
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("%v: %v", f, err)
+		}
+		locale := filepath.Base(filepath.Dir(f))
+		texts[locale] = m
+		found[locale] = true
+	}
+	keys := make([]string, 0, len(texts[DefaultLocale]))
+	for key := range texts[DefaultLocale] {
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	sort.Strings(keys)
+
+	table.Missing = make(map[string][]string)
+	for _, locale := range checkLocales(found) {
+		for _, key := range keys {
+			if texts[locale][key] == "" {
+				table.Missing[locale] = append(table.Missing[locale], key)
+			}
+		}
+	}
+	return nil
+}
+
+//多语言key为 表名.字段名.主键，没有主键时用文本的sha1代替
+func textKey(table *Table, col *Column, row []interface{}, text string) string {
+	if keyColumn(table) != nil {
+		return fmt.Sprintf("%v.%v.%v", table.Name, col.Name, row[0])
+	}
+	sum := sha1.Sum([]byte(text))
+	return fmt.Sprintf("%v.%v.%x", table.Name, col.Name, sum[:4])
+}
+
+//一张表在各语言目录下的文本文件
+func textFiles(name string) ([]string, error) {
+	return filepath.Glob(filepath.Join(outPath(LangDir, "*"), name+".json"))
+}
+
+//输出多语言文本，先删除上次生成的文件
+func writeTexts(table *Table) error {
+	files, err := textFiles(table.Name)
+	if err != nil {
+		return err
+	}
+	for _, f := range files {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
+	}
+
+	for locale, texts := range table.Texts {
+		dir := outPath(LangDir, locale)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		buf := new(bytes.Buffer)
+		enc := json.NewEncoder(buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "\t")
+		if err := enc.Encode(texts); err != nil {
+			return err
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, table.Name+".json"), buf.Bytes(), 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestLocalize(t *testing.T) {
+	defer func(old string) { Locales = old }(Locales)
+	Locales = "en,ja"
+
+	table, err := readSheet("Item.xlsx", "Item", [][]string{
+		{"编号", "名字", "英文名", "数量"},
+		{"#"},
+		{"Id", "Name", "Name@en", "Count"},
+		{"int", "text", "text", "int"},
+		{"1", "剑", "Sword", "1"},
+		{"2", "盾", "", "2"},
+		{"3", "", "", "3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(table.Columns) != 3 || table.Column("Name@en") != nil {
+		t.Errorf("translation column not removed: %v", table.Columns)
+	}
+	if want := []interface{}{int64(1), "Item.Name.1", int64(1)}; !reflect.DeepEqual(table.Rows[0], want) {
+		t.Errorf("got %v, want %v", table.Rows[0], want)
+	}
+	if table.Rows[2][1] != "" {
+		t.Errorf("empty text should stay empty, got %v", table.Rows[2][1])
+	}
+	if table.Texts["zh"]["Item.Name.2"] != "盾" || table.Texts["en"]["Item.Name.1"] != "Sword" {
+		t.Errorf("unexpected texts: %v", table.Texts)
+	}
+	want := map[string][]string{"en": {"Item.Name.2"}, "ja": {"Item.Name.1", "Item.Name.2"}}
+	if !reflect.DeepEqual(table.Missing, want) {
+		t.Errorf("got missing %v, want %v", table.Missing, want)
+	}
+}
+
+func TestLocalizeError(t *testing.T) {
+	_, err := readSheet("Item.xlsx", "Item", [][]string{
+		{"编号", "英文名"},
+		{"#"},
+		{"Id", "Desc@en"},
+		{"int", "text"},
+	})
+	if err == nil {
+		t.Error("expected unknown text field error")
+	}
+}
+
+func TestLoadMissing(t *testing.T) {
+	dir, err := ioutil.TempDir("", "excel2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { *outDir = old }(*outDir)
+	*outDir = dir
+	defer func(old string) { Locales = old }(Locales)
+	Locales = "en,ja"
+	if err := createDirs(false); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := readSheet("Item.xlsx", "Item", [][]string{
+		{"编号", "名字", "英文名"},
+		{"#"},
+		{"Id", "Name", "Name@en"},
+		{"int", "text", "text"},
+		{"1", "剑", "Sword"},
+		{"2", "盾", ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeTable(table); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := loadTables([]*ManifestEntry{newManifestEntry(table, "")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(tables[0].Missing, table.Missing) {
+		t.Errorf("got missing %v, want %v", tables[0].Missing, table.Missing)
+	}
+}
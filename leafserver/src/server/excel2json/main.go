@@ -0,0 +1,351 @@
+//excel2json 将配置表格转换为json，并生成Go、recordfile txt以及客户端代码
+//
+//	excel2json -in 表格目录 -out 输出目录 [-check] [-full] [-format json]
+//
+//默认根据上次的manifest只转换修改过的表格，并输出每张表增删改的行数
+//
+//退出码0表示成功，1表示表格内容有误，2表示参数错误或文件读写失败
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+//退出码
+const (
+	ExitOK      = 0 //转换成功
+	ExitInvalid = 1 //表格内容有误，未生成任何文件
+	ExitError   = 2 //参数错误或文件读写失败
+)
+
+//命令行参数
+var (
+	inDir     = flag.String("in", ".", "directory containing the .xlsx workbooks")
+	outDir    = flag.String("out", ".", "root of relative output directories")
+	include   = flag.String("include", "*.xlsx", "comma separated glob patterns of workbooks to convert")
+	exclude   = flag.String("exclude", "~$*", "comma separated glob patterns of workbooks to skip")
+	check     = flag.Bool("check", false, "read and validate only, write nothing")
+	full      = flag.Bool("full", false, "ignore the previous manifest, clean the outputs and convert everything")
+	errFormat = flag.String("format", "text", "error output format: text or json")
+	quiet     = flag.Bool("q", false, "do not print progress")
+)
+
+func init() {
+	flag.StringVar(&JsonDir, "json", JsonDir, "json output directory")
+	flag.StringVar(&GoDir, "go", GoDir, "Go output directory")
+	flag.StringVar(&TxtDir, "txt", TxtDir, "recordfile txt output directory")
+	flag.StringVar(&CsDir, "cs", CsDir, "C# output directory")
+	flag.StringVar(&TsDir, "ts", TsDir, "TypeScript output directory")
+	flag.StringVar(&PackedDir, "bin", PackedDir, "binary packed output directory, empty to disable")
+	flag.BoolVar(&PackedCompress, "z", PackedCompress, "gzip the binary packed output")
+	flag.StringVar(&LangDir, "lang", LangDir, "localized text output directory")
+	flag.StringVar(&DefaultLocale, "locale", DefaultLocale, "locale of the text columns")
+	flag.StringVar(&Locales, "locales", Locales, "comma separated locales to check for missing translations")
+	flag.StringVar(&GoPackage, "pkg", GoPackage, "package of the generated Go code")
+	flag.StringVar(&CsNamespace, "ns", CsNamespace, "namespace of the generated C# code")
+	flag.StringVar(&SheetPattern, "sheet", SheetPattern, "only convert sheets matching the regexp")
+	flag.StringVar(&RegexpPattern, "skip", RegexpPattern, "skip sheets matching the regexp")
+}
+
+func main() {
+	flag.Parse()
+	if flag.NArg() > 0 || (*errFormat != "text" && *errFormat != "json") {
+		flag.Usage()
+		os.Exit(ExitError)
+	}
+
+	result, err := run()
+	report(result, err)
+	switch err.(type) {
+	case nil:
+		os.Exit(ExitOK)
+	case ErrorList:
+		os.Exit(ExitInvalid)
+	default:
+		os.Exit(ExitError)
+	}
+}
+
+//进度信息输出到标准错误，标准输出留给json格式的报告
+func logf(format string, a ...interface{}) {
+	if *quiet {
+		return
+	}
+	fmt.Fprintf(os.Stderr, format+"\n", a...)
+}
+
+//转换结果
+type Result struct {
+	Changes []*TableDiff        `json:"changes"`
+	Missing map[string][]string `json:"missing"` //语言 -> 缺少翻译的多语言key
+}
+
+//读取修改过的表格，跨表校验通过后只输出变化的表，表格内容的错误以ErrorList返回
+func run() (*Result, error) {
+	files, err := workbooks()
+	if err != nil {
+		return nil, err
+	}
+	var prev Manifest
+	if !*full {
+		if prev, err = readManifest(outPath(JsonDir, ManifestFile)); err != nil {
+			return nil, err
+		}
+	}
+
+	var tables []*Table
+	var errs ErrorList
+	changed := make(map[*Table]bool)
+	hashes := make(map[string]string)
+	options := optionsFingerprint()
+	for _, fileName := range files {
+		hash, err := hashFile(filepath.Join(*inDir, fileName))
+		if err != nil {
+			return nil, err
+		}
+		hashes[fileName] = hash
+		if entries := prev.Unchanged(fileName, hash, options); entries != nil {
+			fileTables, err := loadTables(entries)
+			if err == nil {
+				tables = append(tables, fileTables...)
+				continue
+			}
+			logf("%v 重新转换: %v", fileName, err)
+		}
+
+		logf("当前处理表格文件为：%v", fileName)
+		fileTables, err := readExcel(filepath.Join(*inDir, fileName))
+		if l, ok := err.(ErrorList); ok {
+			errs = append(errs, l)
+			logf("%v 处理失败", fileName)
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		for _, table := range fileTables {
+			changed[table] = true
+		}
+		tables = append(tables, fileTables...)
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	if err := validateTables(tables); err != nil {
+		return nil, err
+	}
+
+	//计算差异，来源表格被删除或sheet被删除的表也要删除输出
+	result := &Result{Changes: []*TableDiff{}, Missing: make(map[string][]string)}
+	var removed []string
+	for _, table := range tables {
+		//缺少的翻译包括未修改的表
+		for locale, keys := range table.Missing {
+			result.Missing[locale] = append(result.Missing[locale], keys...)
+		}
+		if !changed[table] {
+			continue
+		}
+		var old *Table
+		if entry := prev.Find(table.Name); entry != nil {
+			if old, err = loadTable(entry); err != nil {
+				logf("%v 无法比较: %v", table.Name, err)
+			}
+		}
+		diff, err := diffTable(old, table)
+		if err != nil {
+			return nil, err
+		}
+		if diff.Status == "added" || diff.Added+diff.Removed+diff.Changed > 0 {
+			result.Changes = append(result.Changes, diff)
+		}
+	}
+	for _, entry := range prev {
+		if findTable(tables, entry.Name) == nil {
+			removed = append(removed, entry.Name)
+			result.Changes = append(result.Changes, &TableDiff{Table: entry.Name, Status: "removed", Removed: entry.Rows})
+		}
+	}
+	if *check {
+		logf("校验通过，共%v张表", len(tables))
+		return result, nil
+	}
+
+	if err := createDirs(*full); err != nil {
+		return nil, err
+	}
+	for _, name := range removed {
+		if err := removeOutputs(name); err != nil {
+			return nil, err
+		}
+		logf("删除表 [%v]", name)
+	}
+	var manifest Manifest
+	for _, table := range tables {
+		manifest = append(manifest, newManifestEntry(table, hashes[table.File]))
+		if !changed[table] {
+			continue
+		}
+		if err := writeTable(table); err != nil {
+			return nil, err
+		}
+		logf("sheet [%v] 生成 %v.json", table.Sheet, table.Name)
+	}
+	return result, manifest.Write(outPath(JsonDir, ManifestFile))
+}
+
+//加载未修改的表格上次生成的表，任何输出文件缺失时需要重新转换
+func loadTables(entries []*ManifestEntry) ([]*Table, error) {
+	var tables []*Table
+	for _, entry := range entries {
+		for _, f := range outputFiles(entry.Name) {
+			if _, err := os.Stat(f); err != nil {
+				return nil, err
+			}
+		}
+		table, err := loadTable(entry)
+		if err != nil {
+			return nil, err
+		}
+		if err := loadMissing(table); err != nil {
+			return nil, err
+		}
+		tables = append(tables, table)
+	}
+	return tables, nil
+}
+
+func findTable(tables []*Table, name string) *Table {
+	for _, table := range tables {
+		if table.Name == name {
+			return table
+		}
+	}
+	return nil
+}
+
+//输入目录下需要转换的表格文件
+func workbooks() ([]string, error) {
+	fileInfos, err := ioutil.ReadDir(*inDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, v := range fileInfos {
+		if v.IsDir() {
+			continue
+		}
+		ok, err := matchAny(*include, v.Name())
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		skip, err := matchAny(*exclude, v.Name())
+		if err != nil {
+			return nil, err
+		}
+		if !skip {
+			files = append(files, v.Name())
+		}
+	}
+	return files, nil
+}
+
+func matchAny(patterns string, name string) (bool, error) {
+	for _, pattern := range strings.Split(patterns, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern == "" {
+			continue
+		}
+		ok, err := filepath.Match(pattern, name)
+		if err != nil {
+			return false, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+		if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+//json格式报告中的一条错误，行列从1开始
+type errorReport struct {
+	File    string `json:"file,omitempty"`
+	Sheet   string `json:"sheet,omitempty"`
+	Cell    string `json:"cell,omitempty"`
+	Row     int    `json:"row,omitempty"`
+	Col     int    `json:"col,omitempty"`
+	Message string `json:"message"`
+}
+
+//展开嵌套的ErrorList
+func flatten(err error) []error {
+	if l, ok := err.(ErrorList); ok {
+		var errs []error
+		for _, e := range l {
+			errs = append(errs, flatten(e)...)
+		}
+		return errs
+	}
+	return []error{err}
+}
+
+//输出结果和错误，text格式下差异输出到标准输出，缺少的翻译和错误输出到标准错误
+func report(result *Result, err error) {
+	var errs []error
+	if err != nil {
+		errs = flatten(err)
+	}
+	if result == nil {
+		result = &Result{Changes: []*TableDiff{}, Missing: map[string][]string{}}
+	}
+
+	if *errFormat == "text" {
+		for _, d := range result.Changes {
+			fmt.Println(d)
+		}
+		locales := make([]string, 0, len(result.Missing))
+		for locale := range result.Missing {
+			locales = append(locales, locale)
+		}
+		sort.Strings(locales)
+		for _, locale := range locales {
+			keys := result.Missing[locale]
+			fmt.Fprintf(os.Stderr, "语言 %v 缺少%v条翻译: %v\n", locale, len(keys), strings.Join(keys, ", "))
+		}
+		for _, e := range errs {
+			fmt.Fprintln(os.Stderr, e)
+		}
+		return
+	}
+
+	reports := make([]errorReport, 0, len(errs))
+	for _, e := range errs {
+		if ce, ok := e.(*CellError); ok {
+			reports = append(reports, errorReport{
+				File:    ce.File,
+				Sheet:   ce.Sheet,
+				Cell:    cellName(ce.Row, ce.Col),
+				Row:     ce.Row + 1,
+				Col:     ce.Col + 1,
+				Message: ce.Err.Error(),
+			})
+		} else {
+			reports = append(reports, errorReport{Message: e.Error()})
+		}
+	}
+	json.NewEncoder(os.Stdout).Encode(struct {
+		OK     bool          `json:"ok"`
+		Errors []errorReport `json:"errors"`
+		*Result
+	}{len(errs) == 0, reports, result})
+}
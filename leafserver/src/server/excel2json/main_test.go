@@ -0,0 +1,48 @@
+package main
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchAny(t *testing.T) {
+	for name, want := range map[string]bool{"Item.xlsx": true, "~$Item.xlsx": true, "Item.csv": false} {
+		if got, err := matchAny("*.xlsx, ~$*", name); err != nil || got != want {
+			t.Errorf("%v: got %v %v, want %v", name, got, err, want)
+		}
+	}
+	if _, err := matchAny("[", "Item.xlsx"); err == nil {
+		t.Error("expected invalid pattern error")
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	err := ErrorList{errors.New("a"), ErrorList{errors.New("b"), errors.New("c")}}
+	if errs := flatten(err); len(errs) != 3 {
+		t.Errorf("got %v errors, want 3", len(errs))
+	}
+}
+
+func TestRunReadError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "excel2json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "Bad.xlsx"), []byte("not a zip"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	defer func(in, out string, c bool) { *inDir, *outDir, *check = in, out, c }(*inDir, *outDir, *check)
+	*inDir, *outDir, *check = dir, dir, true
+
+	_, err = run()
+	if err == nil {
+		t.Fatal("expected read error")
+	}
+	if _, ok := err.(ErrorList); ok {
+		t.Errorf("read error reported as invalid content: %v", err)
+	}
+}
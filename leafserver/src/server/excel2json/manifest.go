@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+)
+
+//生成的表清单文件，位于JsonDir下，同时用于增量转换
+var ManifestFile = "manifest.json"
+
+//清单中的一张表
+type ManifestEntry struct {
+	Name    string //表名，即sheet名
+	File    string //来源表格文件
+	Sheet   string
+	Hash    string //来源表格文件的sha1
+	Options string //影响输出的参数的指纹，见optionsFingerprint
+	Output  string //生成的json文件
+	Rows    int
+	Columns []ManifestColumn
+	Lines   []int `json:",omitempty"` //每行数据在表格中的行下标
+}
+
+type ManifestColumn struct {
+	Name  string
+	Type  string //类型行中的声明
+	Index int
+}
+
+type Manifest []*ManifestEntry
+
+//读取上次生成的清单，不存在时返回nil
+func readManifest(name string) (Manifest, error) {
+	data, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var m Manifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+//影响输出的参数，修改后全部表格需要重新转换
+func optionsFingerprint() string {
+	options := []string{SheetPattern, RegexpPattern, GoPackage, CsNamespace, DefaultLocale, Locales,
+		GoDir, TxtDir, CsDir, TsDir, PackedDir, LangDir, fmt.Sprint(PackedCompress)}
+	sum := sha1.Sum([]byte(strings.Join(options, "\x00")))
+	return hex.EncodeToString(sum[:])
+}
+
+func newManifestEntry(table *Table, hash string) *ManifestEntry {
+	entry := &ManifestEntry{
+		Name:    table.Name,
+		File:    table.File,
+		Sheet:   table.Sheet,
+		Hash:    hash,
+		Options: optionsFingerprint(),
+		Output:  table.Name + ".json",
+		Rows:    len(table.Rows),
+		Lines:   table.Lines,
+	}
+	for _, col := range table.Columns {
+		entry.Columns = append(entry.Columns, ManifestColumn{
+			Name:  col.Name,
+			Type:  col.Type.Decl,
+			Index: col.Index,
+		})
+	}
+	return entry
+}
+
+func (m Manifest) Find(name string) *ManifestEntry {
+	for _, entry := range m {
+		if entry.Name == name {
+			return entry
+		}
+	}
+	return nil
+}
+
+//表格文件和参数都未修改时返回上次由它生成的表
+func (m Manifest) Unchanged(file string, hash string, options string) []*ManifestEntry {
+	var entries []*ManifestEntry
+	for _, entry := range m {
+		if entry.File != file {
+			continue
+		}
+		if entry.Hash != hash || entry.Options != options {
+			return nil
+		}
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+func (m Manifest) Write(name string) error {
+	if m == nil {
+		m = Manifest{}
+	}
+	data, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(name, data, 0644)
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	Rf "GoLeafServer/leafserver/src/server/recordfile"
+	"os"
+)
+
+//二进制配置目录，为空时不生成
+var PackedDir = ""
+
+//二进制配置是否gzip压缩
+var PackedCompress = false
+
+func packedKind(t *FieldType) uint8 {
+	switch t.Kind {
+	case IntType, RefType:
+		return Rf.PackedInt
+	case FloatType:
+		return Rf.PackedFloat
+	case StringType, EnumType, TextType:
+		return Rf.PackedString
+	case BoolType:
+		return Rf.PackedBool
+	case ArrType:
+		return Rf.PackedArray
+	}
+	return Rf.PackedJSON
+}
+
+//输出二进制配置，由Rf.RecordFile.ReadPacked读取
+func writePacked(table *Table, name string) error {
+	columns := make([]Rf.PackedColumn, len(table.Columns))
+	for i, col := range table.Columns {
+		columns[i] = Rf.PackedColumn{Name: col.Name, Kind: packedKind(col.Type)}
+		if col.Type.Kind == ArrType {
+			columns[i].Elem = packedKind(col.Type.Elem)
+		}
+	}
+
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := Rf.WritePacked(f, columns, table.Rows, PackedCompress); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,288 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//字段类型
+const (
+	IntType    = iota //int
+	FloatType         //float
+	StringType        //string
+	BoolType          //bool
+	ArrType           //[]int []float []string []bool []ref:Table.Field
+	MapType           //map，单元格内容为json对象
+	EnumType          //enum:A|B|C
+	RefType           //ref:Table.Field，引用其它表的主键
+	TextType          //text，需要翻译的文本，输出时替换为多语言key
+)
+
+//类型行中声明的字段类型
+type FieldType struct {
+	Kind     int
+	Decl     string     //原始声明
+	Elem     *FieldType //数组元素类型
+	Enum     []string   //枚举可选值
+	RefTable string     //引用的表
+	RefField string     //引用的字段
+	HasMin   bool       //int和float的取值范围，如int:1..100
+	HasMax   bool
+	Min      float64
+	Max      float64
+}
+
+//表格中的一列
+type Column struct {
+	Name  string
+	Type  *FieldType
+	Index int //在表格中的列下标，从0开始
+}
+
+//一张配置表，Rows中的值与Columns一一对应
+type Table struct {
+	Name    string
+	File    string
+	Sheet   string
+	Columns []*Column
+	Rows    [][]interface{}
+	Lines   []int //Rows对应的表格行下标
+
+	Texts   map[string]map[string]string //语言 -> 多语言key -> 文本
+	Missing map[string][]string          //语言 -> 缺少翻译的多语言key
+}
+
+//按字段名查找列
+func (table *Table) Column(name string) *Column {
+	for _, col := range table.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	return nil
+}
+
+func (table *Table) cellError(row int, col *Column, err error) error {
+	return &CellError{
+		File:  table.File,
+		Sheet: table.Sheet,
+		Row:   row,
+		Col:   col.Index,
+		Err:   fmt.Errorf("field %v: %v", col.Name, err),
+	}
+}
+
+//单元格错误，行列下标从0开始
+type CellError struct {
+	File  string
+	Sheet string
+	Row   int
+	Col   int
+	Err   error
+}
+
+func (e *CellError) Error() string {
+	return fmt.Sprintf("%v[%v] %v (row=%v, col=%v): %v",
+		e.File, e.Sheet, cellName(e.Row, e.Col), e.Row+1, e.Col+1, e.Err)
+}
+
+//多个错误合并输出
+type ErrorList []error
+
+func (l ErrorList) Error() string {
+	s := make([]string, len(l))
+	for i, err := range l {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+//行列下标转换为表格坐标，如(4, 2) -> C5
+func cellName(row, col int) string {
+	name := ""
+	for col++; col > 0; col = (col - 1) / 26 {
+		name = string(rune('A'+(col-1)%26)) + name
+	}
+	return name + strconv.Itoa(row+1)
+}
+
+//解析类型行中的类型声明
+func parseFieldType(decl string) (*FieldType, error) {
+	decl = strings.TrimSpace(decl)
+	t := &FieldType{Decl: decl}
+	switch {
+	case decl == "int":
+		t.Kind = IntType
+	case decl == "float":
+		t.Kind = FloatType
+	case decl == "string":
+		t.Kind = StringType
+	case decl == "text":
+		t.Kind = TextType
+	case decl == "bool":
+		t.Kind = BoolType
+	case decl == "map":
+		t.Kind = MapType
+	case strings.HasPrefix(decl, "int:"), strings.HasPrefix(decl, "float:"):
+		i := strings.Index(decl, ":")
+		if err := t.parseRange(decl[i+1:]); err != nil {
+			return nil, fmt.Errorf("invalid range %q: %v", decl, err)
+		}
+		if decl[:i] == "int" {
+			t.Kind = IntType
+		} else {
+			t.Kind = FloatType
+		}
+	case strings.HasPrefix(decl, "[]"):
+		elem, err := parseFieldType(decl[2:])
+		if err != nil {
+			return nil, err
+		}
+		switch elem.Kind {
+		case IntType, FloatType, StringType, BoolType, RefType:
+		default:
+			return nil, fmt.Errorf("invalid array element type %q", elem.Decl)
+		}
+		t.Kind = ArrType
+		t.Elem = elem
+	case strings.HasPrefix(decl, "enum:"):
+		for _, v := range strings.Split(decl[len("enum:"):], "|") {
+			v = strings.TrimSpace(v)
+			if v == "" {
+				return nil, fmt.Errorf("empty enum value in %q", decl)
+			}
+			t.Enum = append(t.Enum, v)
+		}
+		t.Kind = EnumType
+	case strings.HasPrefix(decl, "ref:"):
+		ref := strings.Split(decl[len("ref:"):], ".")
+		if len(ref) != 2 || ref[0] == "" || ref[1] == "" {
+			return nil, fmt.Errorf("invalid reference %q, want ref:Table.Field", decl)
+		}
+		t.Kind = RefType
+		t.RefTable = ref[0]
+		t.RefField = ref[1]
+	case decl == "":
+		return nil, errors.New("missing field type")
+	default:
+		return nil, fmt.Errorf("unknown field type %q", decl)
+	}
+	return t, nil
+}
+
+//解析取值范围 min..max，可省略一侧
+func (t *FieldType) parseRange(s string) error {
+	r := strings.Split(s, "..")
+	if len(r) != 2 {
+		return errors.New("want min..max")
+	}
+	if r[0] = strings.TrimSpace(r[0]); r[0] != "" {
+		v, err := strconv.ParseFloat(r[0], 64)
+		if err != nil {
+			return err
+		}
+		t.HasMin, t.Min = true, v
+	}
+	if r[1] = strings.TrimSpace(r[1]); r[1] != "" {
+		v, err := strconv.ParseFloat(r[1], 64)
+		if err != nil {
+			return err
+		}
+		t.HasMax, t.Max = true, v
+	}
+	if t.HasMin && t.HasMax && t.Min > t.Max {
+		return errors.New("min is greater than max")
+	}
+	return nil
+}
+
+//按声明的类型解析单元格，空单元格为该类型的零值
+func (t *FieldType) Parse(cell string) (interface{}, error) {
+	if t.Kind != StringType && t.Kind != TextType {
+		cell = strings.TrimSpace(cell)
+	}
+
+	switch t.Kind {
+	case IntType, RefType:
+		if cell == "" {
+			return int64(0), nil
+		}
+		v, err := strconv.ParseInt(cell, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %v %q", t.Decl, cell)
+		}
+		return v, nil
+	case FloatType:
+		if cell == "" {
+			return float64(0), nil
+		}
+		v, err := strconv.ParseFloat(cell, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid float %q", cell)
+		}
+		return v, nil
+	case StringType, TextType:
+		return cell, nil
+	case BoolType:
+		if cell == "" {
+			return false, nil
+		}
+		v, err := strconv.ParseBool(cell)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bool %q", cell)
+		}
+		return v, nil
+	case ArrType:
+		//支持 [1,2,3] 和 1,2,3 两种写法
+		if strings.HasPrefix(cell, "[") {
+			if !strings.HasSuffix(cell, "]") {
+				return nil, fmt.Errorf("invalid %v %q: missing ']'", t.Decl, cell)
+			}
+			cell = strings.TrimSpace(cell[1 : len(cell)-1])
+		}
+		arr := []interface{}{}
+		if cell == "" {
+			return arr, nil
+		}
+		for _, s := range strings.Split(cell, ",") {
+			s = strings.TrimSpace(s)
+			if t.Elem.Kind == StringType {
+				s = strings.Trim(s, "\"")
+			}
+			v, err := t.Elem.Parse(s)
+			if err != nil {
+				return nil, fmt.Errorf("invalid %v %q: %v", t.Decl, cell, err)
+			}
+			arr = append(arr, v)
+		}
+		return arr, nil
+	case MapType:
+		if cell == "" {
+			return json.RawMessage("{}"), nil
+		}
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(cell), &m); err != nil || m == nil {
+			return nil, fmt.Errorf("invalid map %q, want a json object", cell)
+		}
+		buf := new(bytes.Buffer)
+		if err := json.Compact(buf, []byte(cell)); err != nil {
+			return nil, fmt.Errorf("invalid map %q: %v", cell, err)
+		}
+		return json.RawMessage(buf.Bytes()), nil
+	case EnumType:
+		if cell == "" {
+			return "", nil
+		}
+		for _, v := range t.Enum {
+			if v == cell {
+				return cell, nil
+			}
+		}
+		return nil, fmt.Errorf("invalid enum %q, want one of %v", cell, strings.Join(t.Enum, "|"))
+	}
+	return nil, fmt.Errorf("unknown field type %q", t.Decl)
+}
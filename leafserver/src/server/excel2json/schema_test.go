@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseCell(t *testing.T) {
+	cases := []struct {
+		decl string
+		cell string
+		want string
+	}{
+		{"int", "007", "7"},
+		{"string", "007", `"007"`},
+		{"string", "1.2.3", `"1.2.3"`},
+		{"float", "1.5", "1.5"},
+		{"bool", "1", "true"},
+		{"[]int", "[1, 2,3]", "[1,2,3]"},
+		{"[]string", "a,b", `["a","b"]`},
+		{"map", `{"a": 1}`, `{"a":1}`},
+		{"enum:Red|Green", "Green", `"Green"`},
+		{"ref:Item.Id", "1001", "1001"},
+		{"int", "", "0"},
+	}
+	for _, c := range cases {
+		ft, err := parseFieldType(c.decl)
+		if err != nil {
+			t.Fatalf("%v: %v", c.decl, err)
+		}
+		v, err := ft.Parse(c.cell)
+		if err != nil {
+			t.Fatalf("%v %q: %v", c.decl, c.cell, err)
+		}
+		b, _ := json.Marshal(v)
+		if string(b) != c.want {
+			t.Errorf("%v %q: got %s, want %s", c.decl, c.cell, b, c.want)
+		}
+	}
+}
+
+func TestParseCellError(t *testing.T) {
+	cases := [][2]string{
+		{"int", "1.5"},
+		{"float", "abc"},
+		{"bool", "yes"},
+		{"[]int", "[1,a]"},
+		{"map", "[1]"},
+		{"enum:Red|Green", "Blue"},
+	}
+	for _, c := range cases {
+		ft, err := parseFieldType(c[0])
+		if err != nil {
+			t.Fatalf("%v: %v", c[0], err)
+		}
+		if _, err := ft.Parse(c[1]); err == nil {
+			t.Errorf("%v %q: expected error", c[0], c[1])
+		}
+	}
+}
+
+func TestReadSheet(t *testing.T) {
+	rows := [][]string{
+		{"编号", "名字", "数量"},
+		{"#"},
+		{"Id", "Name", "Count"},
+		{"int", "string", "int"},
+		{"1", "sword", "10"},
+		{"2", "007", "x"},
+	}
+	_, err := readSheet("Item.xlsx", "Sheet1", rows)
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 1 {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if e := errs[0].(*CellError); e.Row != 5 || e.Col != 2 {
+		t.Errorf("unexpected cell: %v", e)
+	}
+
+	rows[5][2] = "3"
+	table, err := readSheet("Item.xlsx", "Sheet1", rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(table.Rows) != 2 || table.Rows[1][1] != "007" {
+		t.Errorf("unexpected rows: %v", table.Rows)
+	}
+}
+
+func TestConvertSheet(t *testing.T) {
+	for name, want := range map[string]bool{"Item": true, "#备注": false} {
+		if got, err := convertSheet(name); err != nil || got != want {
+			t.Errorf("%v: got %v %v, want %v", name, got, err, want)
+		}
+	}
+
+	SheetPattern = "^Cfg"
+	defer func() { SheetPattern = "" }()
+	if got, _ := convertSheet("Item"); got {
+		t.Errorf("Item should not match %v", SheetPattern)
+	}
+}
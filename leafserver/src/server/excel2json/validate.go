@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+)
+
+//跨表校验：表名重复、主键重复、取值越界、引用不存在
+func validateTables(tables []*Table) error {
+	var errs ErrorList
+	byName := make(map[string]*Table)
+	for _, table := range tables {
+		if other, ok := byName[table.Name]; ok {
+			errs = append(errs, fmt.Errorf("表 [%v] 重复定义: %v[%v] %v[%v]",
+				table.Name, other.File, other.Sheet, table.File, table.Sheet))
+			continue
+		}
+		byName[table.Name] = table
+	}
+
+	for _, table := range tables {
+		errs = append(errs, checkKeys(table)...)
+		errs = append(errs, checkRanges(table)...)
+	}
+
+	refs := make(map[string]map[int64]bool)
+	for _, table := range tables {
+		for _, col := range table.Columns {
+			ref := col.Type
+			if ref.Kind == ArrType {
+				ref = ref.Elem
+			}
+			if ref.Kind != RefType {
+				continue
+			}
+			values, err := refValues(byName, ref, refs)
+			if err != nil {
+				errs = append(errs, table.cellError(TypeRow, col, err))
+				continue
+			}
+			for i, row := range table.Rows {
+				for _, id := range refIds(row[columnIndex(table, col)]) {
+					//0表示不引用
+					if id == 0 || values[id] {
+						continue
+					}
+					errs = append(errs, table.cellError(table.Lines[i], col,
+						fmt.Errorf("%v not found in %v.%v", id, ref.RefTable, ref.RefField)))
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+//主键（第一列）不能重复
+func checkKeys(table *Table) []error {
+	key := keyColumn(table)
+	if key == nil {
+		return nil
+	}
+	var errs []error
+	seen := make(map[interface{}]int)
+	for i, row := range table.Rows {
+		v := row[0]
+		if line, ok := seen[v]; ok {
+			errs = append(errs, table.cellError(table.Lines[i], key,
+				fmt.Errorf("duplicate key %v, first defined at row %v", v, line+1)))
+			continue
+		}
+		seen[v] = table.Lines[i]
+	}
+	return errs
+}
+
+//检查int和float声明的取值范围
+func checkRanges(table *Table) []error {
+	var errs []error
+	for k, col := range table.Columns {
+		t := col.Type
+		if t.Kind == ArrType {
+			t = t.Elem
+		}
+		if !t.HasMin && !t.HasMax {
+			continue
+		}
+		for i, row := range table.Rows {
+			values := []interface{}{row[k]}
+			if arr, ok := row[k].([]interface{}); ok {
+				values = arr
+			}
+			for _, v := range values {
+				var f float64
+				switch v := v.(type) {
+				case int64:
+					f = float64(v)
+				case float64:
+					f = v
+				default:
+					continue
+				}
+				if (t.HasMin && f < t.Min) || (t.HasMax && f > t.Max) {
+					errs = append(errs, table.cellError(table.Lines[i], col,
+						fmt.Errorf("value %v out of range %v", v, t.Decl)))
+				}
+			}
+		}
+	}
+	return errs
+}
+
+//被引用的列的全部取值
+func refValues(byName map[string]*Table, ref *FieldType, cache map[string]map[int64]bool) (map[int64]bool, error) {
+	name := ref.RefTable + "." + ref.RefField
+	if values, ok := cache[name]; ok {
+		return values, nil
+	}
+	target, ok := byName[ref.RefTable]
+	if !ok {
+		return nil, fmt.Errorf("reference to unknown table %v", ref.RefTable)
+	}
+	col := target.Column(ref.RefField)
+	if col == nil {
+		return nil, fmt.Errorf("reference to unknown field %v", name)
+	}
+	if col.Type.Kind != IntType && col.Type.Kind != RefType {
+		return nil, fmt.Errorf("referenced field %v must be int", name)
+	}
+	k := columnIndex(target, col)
+	values := make(map[int64]bool, len(target.Rows))
+	for _, row := range target.Rows {
+		values[row[k].(int64)] = true
+	}
+	cache[name] = values
+	return values, nil
+}
+
+func refIds(v interface{}) []int64 {
+	switch v := v.(type) {
+	case int64:
+		return []int64{v}
+	case []interface{}:
+		ids := make([]int64, 0, len(v))
+		for _, id := range v {
+			ids = append(ids, id.(int64))
+		}
+		return ids
+	}
+	return nil
+}
+
+func columnIndex(table *Table, col *Column) int {
+	for k, c := range table.Columns {
+		if c == col {
+			return k
+		}
+	}
+	return -1
+}
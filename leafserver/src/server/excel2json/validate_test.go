@@ -0,0 +1,47 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateTables(t *testing.T) {
+	item, err := readSheet("Item.xlsx", "Item", [][]string{
+		{"编号", "等级"},
+		{"#"},
+		{"Id", "Level"},
+		{"int", "int:1..10"},
+		{"1001", "1"},
+		{"1002", "11"},
+		{"1002", "5"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	drop, err := readSheet("Drop.xlsx", "Drop", [][]string{
+		{"编号", "物品"},
+		{"#"},
+		{"Id", "Items"},
+		{"int", "[]ref:Item.Id"},
+		{"1", "1001,1003"},
+		{"2", ""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = validateTables([]*Table{item, drop})
+	errs, ok := err.(ErrorList)
+	if !ok || len(errs) != 3 {
+		t.Fatalf("unexpected errors: %v", err)
+	}
+	for i, s := range []string{
+		"Item.xlsx[Item] A7 (row=7, col=1): field Id: duplicate key 1002, first defined at row 6",
+		"Item.xlsx[Item] B6 (row=6, col=2): field Level: value 11 out of range int:1..10",
+		"Drop.xlsx[Drop] B5 (row=5, col=2): field Items: 1003 not found in Item.Id",
+	} {
+		if !strings.Contains(errs[i].Error(), s) {
+			t.Errorf("got %q, want %q", errs[i], s)
+		}
+	}
+}
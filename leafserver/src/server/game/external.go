@@ -9,4 +9,8 @@ var (
 	Module  = new(internal.Module)
 	// 暴露 ChanRPC
 	ChanRPC = internal.ChanRPC
+	// 已认证的连接，可以按用户 id 查找
+	Sessions = internal.Sessions
+	// 分组广播，可以在任何模块中使用
+	Groups = internal.Groups
 )
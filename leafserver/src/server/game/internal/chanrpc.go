@@ -2,11 +2,26 @@ package internal
 
 import (
 	"github.com/name5566/leaf/gate"
+	"github.com/name5566/leaf/log"
+
+	"GoLeafServer/leafserver/src/server/group"
+	"GoLeafServer/leafserver/src/server/msg"
+	"GoLeafServer/leafserver/src/server/session"
 )
 
+// 已认证的连接，同一个用户重复登录时踢掉旧的连接
+var Sessions = session.NewManager(func(reason string) interface{} {
+	return &msg.Kick{Reason: reason}
+})
+
+// 房间、公会等分组，广播时消息只编码一次
+var Groups = group.NewManager(msg.Processor)
+
 func init() {
 	skeleton.RegisterChanRPC("NewAgent", rpcNewAgent)
 	skeleton.RegisterChanRPC("CloseAgent", rpcCloseAgent)
+	skeleton.RegisterChanRPC("AuthAgent", rpcAuthAgent)
+	skeleton.RegisterChanRPC("ResumeAgent", rpcResumeAgent)
 }
 
 func rpcNewAgent(args []interface{}) {
@@ -16,5 +31,25 @@ func rpcNewAgent(args []interface{}) {
 
 func rpcCloseAgent(args []interface{}) {
 	a := args[0].(gate.Agent)
-	_ = a
+	if reason := args[1].(string); reason != "" {
+		log.Debug("close %v: %v", a.RemoteAddr(), reason)
+	}
+	Sessions.Unbind(a)
+	Groups.LeaveAll(a)
+}
+
+// 连接通过认证，参数为 agent 和用户 id
+func rpcAuthAgent(args []interface{}) {
+	a := args[0].(gate.Agent)
+	userID := args[1].(string)
+	log.Debug("user %v authenticated from %v", userID, a.RemoteAddr())
+	Sessions.Bind(userID, a)
+}
+
+// 断线的连接在 ResumeGrace 内恢复了会话，期间没有收到 CloseAgent，玩家状态不变
+func rpcResumeAgent(args []interface{}) {
+	a := args[0].(gate.Agent)
+	if s := Sessions.ByAgent(a); s != nil {
+		log.Debug("user %v resumed from %v", s.UserID, a.RemoteAddr())
+	}
 }
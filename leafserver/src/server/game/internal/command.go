@@ -0,0 +1,57 @@
+package internal
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"GoLeafServer/leafserver/src/server/session"
+)
+
+func init() {
+	skeleton.RegisterCommand("sessions", "list online users", commandSessions)
+	skeleton.RegisterCommand("kick", "kick a user: kick userid [reason]", commandKick)
+	skeleton.RegisterCommand("groups", "list broadcast groups", commandGroups)
+}
+
+func commandSessions(args []interface{}) interface{} {
+	var lines []string
+	Sessions.Range(func(s *session.Session) bool {
+		lines = append(lines, fmt.Sprintf("%v %v", s.UserID, s.Agent.RemoteAddr()))
+		return true
+	})
+	sort.Strings(lines)
+	lines = append(lines, fmt.Sprintf("%v online", len(lines)))
+	return strings.Join(lines, "\r\n")
+}
+
+func commandKick(args []interface{}) interface{} {
+	if len(args) == 0 {
+		return "usage: kick userid [reason]"
+	}
+	userID := args[0].(string)
+	reason := "kicked"
+	if len(args) > 1 {
+		parts := make([]string, len(args)-1)
+		for i, arg := range args[1:] {
+			parts[i] = arg.(string)
+		}
+		reason = strings.Join(parts, " ")
+	}
+	if !Sessions.Kick(userID, reason) {
+		return fmt.Sprintf("%v is not online", userID)
+	}
+	return fmt.Sprintf("%v kicked: %v", userID, reason)
+}
+
+func commandGroups(args []interface{}) interface{} {
+	names := Groups.Names()
+	if len(names) == 0 {
+		return "no groups"
+	}
+	lines := make([]string, len(names))
+	for i, name := range names {
+		lines[i] = fmt.Sprintf("%v %v", name, Groups.Len(name))
+	}
+	return strings.Join(lines, "\r\n")
+}
@@ -16,8 +16,9 @@ type Module struct {
 
 func (m *Module) OnInit() {
 	m.Skeleton = skeleton
+	watchSIGHUP()
 }
 
 func (m *Module) OnDestroy() {
-
+	stopSIGHUP()
 }
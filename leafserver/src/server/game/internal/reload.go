@@ -0,0 +1,96 @@
+package internal
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"github.com/name5566/leaf/log"
+
+	"GoLeafServer/leafserver/src/server/conf"
+	"GoLeafServer/leafserver/src/server/gamedata"
+)
+
+var hup chan os.Signal
+
+func init() {
+	skeleton.RegisterCommand("reload", "reload changed gamedata tables", gamedata.CommandReload)
+	skeleton.RegisterChanRPC(gamedata.ReloadRPC, rpcGamedataReloaded)
+	gamedata.Subscribe(ChanRPC)
+
+	skeleton.RegisterCommand("reloadconf", "reload server config", commandReloadConf)
+	skeleton.RegisterCommand("conf", "print effective server config", commandConf)
+	skeleton.RegisterChanRPC("SIGHUP", rpcSIGHUP)
+	skeleton.RegisterChanRPC(conf.ReloadRPC, rpcConfReloaded)
+	conf.Subscribe(ChanRPC)
+}
+
+// 配置表重载后，在这里刷新缓存了配置数据的状态
+func rpcGamedataReloaded(args []interface{}) {
+	names := args[0].([]string)
+	log.Debug("gamedata reloaded: %v", strings.Join(names, ", "))
+}
+
+// 收到 SIGHUP 时在模块的 goroutine 中重新加载配置
+func watchSIGHUP() {
+	hup = make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			ChanRPC.Go("SIGHUP")
+		}
+	}()
+}
+
+func stopSIGHUP() {
+	signal.Stop(hup)
+	close(hup)
+}
+
+func rpcSIGHUP(args []interface{}) {
+	reloadConf()
+}
+
+func commandReloadConf(args []interface{}) interface{} {
+	return reloadConf()
+}
+
+func commandConf(args []interface{}) interface{} {
+	var b strings.Builder
+	conf.Print(&b)
+	return strings.TrimSuffix(strings.Replace(b.String(), "\n", "\r\n", -1), "\r\n")
+}
+
+func reloadConf() string {
+	applied, restart, err := conf.Reload()
+	if err != nil {
+		log.Error("reload conf: %v", err)
+		return "reload failed: " + err.Error()
+	}
+	output := "nothing changed"
+	if len(applied) > 0 {
+		output = "applied: " + strings.Join(applied, ", ")
+	}
+	if len(restart) > 0 {
+		output = fmt.Sprintf("%v\r\nneed restart: %v", output, strings.Join(restart, ", "))
+	}
+	log.Release("reload conf: %v", strings.Replace(output, "\r\n", "; ", -1))
+	return output
+}
+
+func rpcConfReloaded(args []interface{}) {
+	names := args[0].([]string)
+	log.Debug("conf reloaded: %v", strings.Join(names, ", "))
+
+	//换了数据包或校验值时，用新的数据包重载配置表
+	for _, name := range names {
+		if name == "GamedataBundle" || name == "GamedataChecksum" {
+			c := conf.Current()
+			gamedata.SetBundle(c.GamedataBundle, c.GamedataChecksum)
+			gamedata.CommandReload(nil)
+			return
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package gamedata
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// 数据包为 zip 文件，BundleManifest 记录版本和每个文件的 sha256
+const BundleManifest = "bundle.json"
+
+type bundleManifest struct {
+	Version string            `json:"version"`
+	Files   map[string]string `json:"files"`
+}
+
+// 将目录下的文件打包，返回数据包的 sha256
+func WriteBundle(w io.Writer, dir string, version string) (string, error) {
+	manifest := bundleManifest{Version: version, Files: make(map[string]string)}
+	var names []string
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+		name, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		names = append(names, filepath.ToSlash(name))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	sort.Strings(names)
+
+	h := sha256.New()
+	zw := zip.NewWriter(io.MultiWriter(w, h))
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		manifest.Files[name] = hex.EncodeToString(sum[:])
+		f, err := zw.Create(name)
+		if err != nil {
+			return "", err
+		}
+		if _, err := f.Write(data); err != nil {
+			return "", err
+		}
+	}
+	data, err := json.MarshalIndent(manifest, "", "\t")
+	if err != nil {
+		return "", err
+	}
+	f, err := zw.Create(BundleManifest)
+	if err != nil {
+		return "", err
+	}
+	if _, err := f.Write(data); err != nil {
+		return "", err
+	}
+	if err := zw.Close(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// 打开数据包，校验整个文件和其中每个文件的 sha256
+func openBundle(name string, checksum string) (fs.FS, string, error) {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, "", err
+	}
+	if checksum != "" {
+		sum := sha256.Sum256(data)
+		if !strings.EqualFold(hex.EncodeToString(sum[:]), checksum) {
+			return nil, "", fmt.Errorf("%v: checksum mismatch", name)
+		}
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, "", fmt.Errorf("%v: %v", name, err)
+	}
+
+	var manifest bundleManifest
+	b, err := fs.ReadFile(zr, BundleManifest)
+	if err != nil {
+		return nil, "", fmt.Errorf("%v: %v", name, err)
+	}
+	if err := json.Unmarshal(b, &manifest); err != nil {
+		return nil, "", fmt.Errorf("%v: %v: %v", name, BundleManifest, err)
+	}
+	for _, f := range zr.File {
+		if f.Name == BundleManifest || strings.HasSuffix(f.Name, "/") {
+			continue
+		}
+		want, ok := manifest.Files[f.Name]
+		if !ok {
+			return nil, "", fmt.Errorf("%v: %v is not in %v", name, f.Name, BundleManifest)
+		}
+		b, err := fs.ReadFile(zr, f.Name)
+		if err != nil {
+			return nil, "", fmt.Errorf("%v: %v", name, err)
+		}
+		sum := sha256.Sum256(b)
+		if hex.EncodeToString(sum[:]) != want {
+			return nil, "", fmt.Errorf("%v: %v checksum mismatch", name, f.Name)
+		}
+		delete(manifest.Files, f.Name)
+	}
+	for f := range manifest.Files {
+		return nil, "", fmt.Errorf("%v: %v is missing", name, f)
+	}
+	return zr, manifest.Version, nil
+}
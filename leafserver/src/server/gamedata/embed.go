@@ -0,0 +1,21 @@
+//go:build gamedata_embed
+
+package gamedata
+
+import (
+	"embed"
+	"io/fs"
+)
+
+// 编译前将配置文件复制到 data 目录
+//
+//go:embed data
+var embedData embed.FS
+
+func embeddedFS() fs.FS {
+	fsys, err := fs.Sub(embedData, "data")
+	if err != nil {
+		panic(err)
+	}
+	return fsys
+}
@@ -0,0 +1,9 @@
+//go:build !gamedata_embed
+
+package gamedata
+
+import "io/fs"
+
+func embeddedFS() fs.FS {
+	return nil
+}
@@ -1,21 +1,258 @@
 package gamedata
 
 import (
-	"github.com/name5566/leaf/log"
-	"github.com/name5566/leaf/recordfile"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
 	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/name5566/leaf/chanrpc"
+	"github.com/name5566/leaf/log"
+
+	Rf "GoLeafServer/leafserver/src/server/recordfile"
+)
+
+// 配置文件的扩展名，决定读取格式
+var Ext = ".txt"
+
+// 重载成功后通知订阅模块的 ChanRPC id，参数为重载的表名 []string
+const ReloadRPC = "GamedataReloaded"
+
+// 一张配置表，读取时总是取当前快照中的数据，重载时整体替换
+type Table struct {
+	name string
+	st   interface{}
+	hash string
+}
+
+func (t *Table) Name() string {
+	return t.name
+}
+
+func (t *Table) RecordFile() *Rf.RecordFile {
+	rf := current.Load().(*Snapshot).Get(t)
+	if rf == nil {
+		panic(fmt.Sprintf("gamedata %v is not loaded", t.name))
+	}
+	return rf
+}
+
+func (t *Table) Record(i int) interface{} {
+	return t.RecordFile().Record(i)
+}
+
+func (t *Table) NumRecord() int {
+	return t.RecordFile().NumRecord()
+}
+
+func (t *Table) Indexes(i int) Rf.Index {
+	return t.RecordFile().Indexes(i)
+}
+
+func (t *Table) Index(i interface{}) interface{} {
+	return t.RecordFile().Index(i)
+}
+
+// 所有表某一时刻的数据，校验函数在新快照上执行
+type Snapshot struct {
+	rfs     map[*Table]*Rf.RecordFile
+	version string
+}
+
+func (s *Snapshot) Get(t *Table) *Rf.RecordFile {
+	return s.rfs[t]
+}
+
+// 数据包的版本，从目录读取时为空
+func (s *Snapshot) Version() string {
+	return s.version
+}
+
+func (s *Snapshot) clone() *Snapshot {
+	rfs := make(map[*Table]*Rf.RecordFile, len(s.rfs)+1)
+	for t, rf := range s.rfs {
+		rfs[t] = rf
+	}
+	return &Snapshot{rfs: rfs, version: s.version}
+}
+
+var (
+	mu          sync.Mutex
+	tables      []*Table
+	validators  []func(s *Snapshot) error
+	subscribers []*chanrpc.Server
+	current     atomic.Value
 )
 
-func readRf(st interface{}) *recordfile.RecordFile {
-	rf, err := recordfile.New(st)
+func init() {
+	current.Store(&Snapshot{rfs: map[*Table]*Rf.RecordFile{}})
+}
+
+// 注册配置表，数据在 Load 时读取
+func readRf(st interface{}) *Table {
+	t, err := register(st)
 	if err != nil {
 		log.Fatal("%v", err)
 	}
-	fn := reflect.TypeOf(st).Name() + ".txt"
-	err = rf.Read("gamedata/" + fn)
+	return t
+}
+
+// 表名为结构体名，不能重复
+func register(st interface{}) (*Table, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, err := Rf.New(st); err != nil {
+		return nil, err
+	}
+	t := &Table{name: reflect.TypeOf(st).Name(), st: st}
+	for _, other := range tables {
+		if other.name == t.name {
+			return nil, fmt.Errorf("duplicate gamedata table %v", t.name)
+		}
+	}
+	tables = append(tables, t)
+
+	return t, nil
+}
+
+func (t *Table) fileName() string {
+	return t.name + Ext
+}
+
+func (t *Table) load(fsys fs.FS) (*Rf.RecordFile, string, error) {
+	fn := t.fileName()
+	data, err := fs.ReadFile(fsys, fn)
 	if err != nil {
-		log.Fatal("%v: %v", fn, err)
+		return nil, "", err
 	}
+	sum := sha1.Sum(data)
+	rf, err := Rf.New(t.st)
+	if err != nil {
+		return nil, "", err
+	}
+	// 与 leaf recordfile 相同，txt 使用 tab 分隔
+	rf.Comma = '\t'
+	err = rf.ReadFS(fsys, fn)
+	if err != nil {
+		return nil, "", err
+	}
+	return rf, hex.EncodeToString(sum[:]), nil
+}
 
-	return rf
+// 当前数据包的版本
+func Version() string {
+	return current.Load().(*Snapshot).Version()
+}
+
+// 注册校验函数，跨表引用等检查写在这里，Load 和 Reload 时在新数据上调用
+func AddValidator(f func(s *Snapshot) error) {
+	mu.Lock()
+	defer mu.Unlock()
+	validators = append(validators, f)
+}
+
+// 订阅重载通知，需要先在 server 上注册 ReloadRPC
+func Subscribe(server *chanrpc.Server) {
+	mu.Lock()
+	defer mu.Unlock()
+	subscribers = append(subscribers, server)
+}
+
+func validate(s *Snapshot) error {
+	var errs []string
+	for _, f := range validators {
+		if err := f(s); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		return errors.New(strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// 读取全部配置表并校验，启动时在 leaf.Run 之前调用
+func Load() error {
+	mu.Lock()
+	defer mu.Unlock()
+
+	_, err := load(true)
+	return err
+}
+
+// 重新读取内容有变化的表，全部读取和校验通过后一次性替换，失败时保留原来的数据
+func Reload() ([]string, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	names, err := load(false)
+	if err != nil || len(names) == 0 {
+		return nil, err
+	}
+	// Reload 通常在订阅模块自己的 goroutine 中执行，ChanRPC 满时同步发送会等待自己
+	for _, server := range subscribers {
+		go server.Go(ReloadRPC, names)
+	}
+	return names, nil
+}
+
+func load(all bool) ([]string, error) {
+	fsys, version, err := openSource()
+	if err != nil {
+		return nil, err
+	}
+
+	s := current.Load().(*Snapshot).clone()
+	s.version = version
+	hashes := make(map[*Table]string)
+	var names []string
+	var errs []string
+	for _, t := range tables {
+		rf, hash, err := t.load(fsys)
+		if err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if !all && hash == t.hash {
+			continue
+		}
+		s.rfs[t] = rf
+		hashes[t] = hash
+		names = append(names, t.name)
+	}
+	if len(errs) > 0 {
+		return nil, errors.New(strings.Join(errs, "; "))
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if err := validate(s); err != nil {
+		return nil, err
+	}
+
+	current.Store(s)
+	for t, hash := range hashes {
+		t.hash = hash
+	}
+	return names, nil
+}
+
+// 控制台命令 reload
+func CommandReload(args []interface{}) interface{} {
+	names, err := Reload()
+	if err != nil {
+		log.Error("reload gamedata: %v", err)
+		return "reload failed: " + err.Error()
+	}
+	if len(names) == 0 {
+		return "nothing changed"
+	}
+	log.Release("gamedata reloaded: %v", strings.Join(names, ", "))
+	return "reloaded: " + strings.Join(names, ", ")
 }
@@ -0,0 +1,208 @@
+package gamedata
+
+import (
+	"errors"
+	"io/fs"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/name5566/leaf/chanrpc"
+
+	Rf "GoLeafServer/leafserver/src/server/recordfile"
+)
+
+type Item struct {
+	Id   int
+	Name string
+}
+
+func writeTestFile(t *testing.T, content string) {
+	err := ioutil.WriteFile(filepath.Join(Dir, "Item.txt"), []byte(content), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+// 测试结束后清空注册的表、校验函数和订阅，测试可以重复运行
+func resetRegistry(t *testing.T) {
+	t.Cleanup(func() {
+		mu.Lock()
+		defer mu.Unlock()
+		tables = nil
+		validators = nil
+		subscribers = nil
+		current.Store(&Snapshot{rfs: map[*Table]*Rf.RecordFile{}})
+	})
+}
+
+func TestRegister(t *testing.T) {
+	resetRegistry(t)
+	if _, err := register(Item{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := register(Item{}); err == nil {
+		t.Error("expected duplicate table error")
+	}
+}
+
+func TestReload(t *testing.T) {
+	resetRegistry(t)
+	dir, err := ioutil.TempDir("", "gamedata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { Dir = old }(Dir)
+	Dir = dir
+
+	writeTestFile(t, "Id\tName\n1\tsword\n")
+	table := readRf(Item{})
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+	if r := table.Record(0).(*Item); r.Name != "sword" {
+		t.Fatalf("got %v", r.Name)
+	}
+
+	// 没有缓冲且没有 goroutine 读取，Reload 不能等待通知发送完成
+	server := chanrpc.NewServer(0)
+	server.Register(ReloadRPC, func(args []interface{}) {})
+	Subscribe(server)
+
+	names, err := Reload()
+	if err != nil || names != nil {
+		t.Fatalf("unchanged reload: %v %v", names, err)
+	}
+
+	//解析失败时保留原来的数据
+	writeTestFile(t, "Id\tName\nx\tshield\n")
+	if _, err := Reload(); err == nil {
+		t.Fatal("expected parse error")
+	}
+	if r := table.Record(0).(*Item); r.Name != "sword" {
+		t.Fatalf("got %v after failed reload", r.Name)
+	}
+
+	//校验失败时保留原来的数据
+	valid := false
+	AddValidator(func(s *Snapshot) error {
+		if !valid {
+			return errors.New("invalid")
+		}
+		return nil
+	})
+	writeTestFile(t, "Id\tName\n1\tshield\n")
+	if _, err := Reload(); err == nil {
+		t.Fatal("expected validation error")
+	}
+	if r := table.Record(0).(*Item); r.Name != "sword" {
+		t.Fatalf("got %v after failed validation", r.Name)
+	}
+
+	valid = true
+	names, err = Reload()
+	if err != nil || !reflect.DeepEqual(names, []string{"Item"}) {
+		t.Fatalf("reload: %v %v", names, err)
+	}
+	if r := table.Record(0).(*Item); r.Name != "shield" {
+		t.Fatalf("got %v after reload", r.Name)
+	}
+	select {
+	case <-server.ChanCall:
+	case <-time.After(time.Second):
+		t.Fatal("no reload notification")
+	}
+}
+
+func TestBundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gamedata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	src := filepath.Join(dir, "src")
+	os.Mkdir(src, 0755)
+	ioutil.WriteFile(filepath.Join(src, "Item.txt"), []byte("Id\tName\n1\tsword\n"), 0644)
+
+	name := filepath.Join(dir, "gamedata.zip")
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := WriteBundle(f, src, "1.0.0")
+	f.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fsys, version, err := openBundle(name, sum)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != "1.0.0" {
+		t.Errorf("got version %v", version)
+	}
+	if data, err := fs.ReadFile(fsys, "Item.txt"); err != nil || string(data) != "Id\tName\n1\tsword\n" {
+		t.Errorf("got %q %v", data, err)
+	}
+	if _, _, err := openBundle(name, strings.Repeat("0", 64)); err == nil {
+		t.Error("expected checksum mismatch")
+	}
+}
+
+func TestReloadBundle(t *testing.T) {
+	resetRegistry(t)
+	dir, err := ioutil.TempDir("", "gamedata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer SetBundle(Bundle, Checksum)
+	src := filepath.Join(dir, "src")
+	os.Mkdir(src, 0755)
+	name := filepath.Join(dir, "gamedata.zip")
+	writeBundle := func(data string, version string) string {
+		ioutil.WriteFile(filepath.Join(src, "Item.txt"), []byte(data), 0644)
+		f, err := os.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+		sum, err := WriteBundle(f, src, version)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return sum
+	}
+
+	table := readRf(Item{})
+	SetBundle(name, writeBundle("Id\tName\n1\tsword\n", "1.0.0"))
+	if err := Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	//数据包换了，校验值没换时不能重载
+	sum := writeBundle("Id\tName\n1\taxe\n", "2.0.0")
+	if _, err := Reload(); err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+	SetBundle(name, sum)
+	names, err := Reload()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(names) != 1 || names[0] != "Item" {
+		t.Errorf("got %v", names)
+	}
+	if v := Version(); v != "2.0.0" {
+		t.Errorf("got version %v", v)
+	}
+	if r := table.Record(0).(*Item); r.Name != "axe" {
+		t.Errorf("got %+v", r)
+	}
+}
@@ -0,0 +1,69 @@
+package gamedata
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// 配置数据的来源，优先级为 Bundle、编译进程序的数据、Dir
+var (
+	// 配置文件所在目录，相对路径先在工作目录下查找，再在程序所在目录下查找
+	Dir = "gamedata"
+	// 数据包路径，见 WriteBundle
+	Bundle = ""
+	// 数据包的 sha256，不为空时校验
+	Checksum = ""
+)
+
+// 修改数据包和校验值，下一次 Load 或 Reload 时生效，可以在任何 goroutine 中调用
+func SetBundle(bundle string, checksum string) {
+	mu.Lock()
+	defer mu.Unlock()
+	Bundle = bundle
+	Checksum = checksum
+}
+
+// 使用 -tags gamedata_embed 编译时为 data 目录下的文件
+var embedded = embeddedFS()
+
+func openSource() (fs.FS, string, error) {
+	if Bundle != "" {
+		return openBundle(resolve(Bundle), Checksum)
+	}
+	if embedded != nil {
+		return embedded, "embedded", nil
+	}
+	dir := resolve(Dir)
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		return nil, "", fmt.Errorf("%v is not a directory", dir)
+	}
+	return os.DirFS(dir), "", nil
+}
+
+func resolve(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	if exists(path) {
+		return path
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return path
+	}
+	if p := filepath.Join(filepath.Dir(exe), path); exists(p) {
+		return p
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -5,5 +5,6 @@ import (
 )
 
 var (
-	Module = new(internal.Module)
+	Module  = new(internal.Module)
+	ChanRPC = internal.ChanRPC
 )
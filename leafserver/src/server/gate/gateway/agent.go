@@ -0,0 +1,430 @@
+package gateway
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/name5566/leaf/log"
+	"github.com/name5566/leaf/network"
+)
+
+// 每个连接创建一个 agent，恢复会话时新连接交给原来的 agent，AgentChanRPC 看到的始终是原来的 agent
+type agent struct {
+	gate     *Gate
+	own      network.Conn // 创建时的连接
+	ip       string
+	counted  bool // 计入了连接数和 ip 的连接数，没有计入的连接已经关闭
+	userData interface{}
+	userID   string
+	authed   int32
+	final    int32 // 不再等待恢复
+	reason   atomic.Value
+
+	mu        sync.Mutex
+	conn      network.Conn // 当前的连接
+	online    bool
+	closed    bool // 已经通知了 CloseAgent
+	resume    *resumeBuffer
+	grace     *time.Timer
+	resumedTo *agent // 这个连接恢复的会话
+}
+
+func (a *agent) Run() {
+	if !a.counted {
+		return
+	}
+	if next := a.serve(a.own); next != nil {
+		a.resumedTo = next
+		next.serve(a.own)
+	}
+}
+
+// 读取 conn 上的消息，客户端恢复了其他会话时返回该会话，由调用者继续读取
+func (a *agent) serve(conn network.Conn) *agent {
+	if timeout := a.gate.limit().HandshakeTimeout; a.gate.AuthRequired && timeout > 0 && !a.authenticated() {
+		t := time.AfterFunc(timeout, func() {
+			if !a.authenticated() {
+				log.Debug("handshake timeout: %v", conn.RemoteAddr())
+				a.reason.Store("handshake timeout")
+				conn.Close()
+			}
+		})
+		defer t.Stop()
+	}
+	lastRead := time.Now().UnixNano()
+	done := make(chan struct{})
+	defer close(done)
+	go a.keepalive(conn, &lastRead, done)
+	limiter := newLimiter(a.gate.limit(), time.Now())
+
+	for {
+		data, err := conn.ReadMsg()
+		if err != nil {
+			log.Debug("read message: %v", err)
+			return nil
+		}
+		now := time.Now()
+		atomic.StoreInt64(&lastRead, now.UnixNano())
+
+		if a.gate.Processor != nil {
+			msg, err := a.gate.Processor.Unmarshal(data)
+			if err != nil {
+				log.Debug("unmarshal message error: %v", err)
+				break
+			}
+			// 心跳不计入限制，也不计入序号
+			if a.gate.isMsg(msg, a.gate.PingMsg) {
+				if err := a.write(conn, a.gate.PongMsg); err != nil {
+					log.Debug("write pong error: %v", err)
+				}
+				continue
+			}
+			if a.gate.isMsg(msg, a.gate.PongMsg) {
+				continue
+			}
+			if limit := a.gate.checkLimit(limiter, msg, len(data), now); limit != "" {
+				action := a.gate.limit().RateLimitAction
+				a.gate.stats.add(limit, action)
+				if action == LimitDisconnect {
+					log.Debug("%v exceeded by %v, disconnect", limit, conn.RemoteAddr())
+					a.reason.Store(limit + " exceeded")
+					break
+				}
+				if action == LimitWarn {
+					log.Release("%v exceeded by %v", limit, conn.RemoteAddr())
+				} else {
+					continue
+				}
+			}
+			if seq, ok := a.gate.parseAck(msg); ok {
+				a.ack(seq)
+				continue
+			}
+			if token, seq, ok := a.gate.parseResume(msg); ok {
+				next, err := a.gate.resumeSession(a, conn, token, seq)
+				if err != nil {
+					log.Debug("resume %v error: %v", conn.RemoteAddr(), err)
+					a.WriteMsg(a.gate.ResumeResult(err))
+					break
+				}
+				return next
+			}
+			if a.gate.isMsg(msg, a.gate.AuthMsg) {
+				if err := a.auth(msg); err != nil {
+					log.Debug("auth %v error: %v", conn.RemoteAddr(), err)
+					break
+				}
+				continue
+			}
+			if a.gate.AuthRequired && !a.authenticated() && !a.gate.isHandshakeMsg(msg) {
+				log.Debug("reject message %v before auth from %v", reflect.TypeOf(msg), conn.RemoteAddr())
+				continue
+			}
+			err = a.gate.Processor.Route(msg, a)
+			if err != nil {
+				log.Debug("route message error: %v", err)
+				break
+			}
+		}
+	}
+	// 服务端主动断开的连接不再恢复
+	atomic.StoreInt32(&a.final, 1)
+	return nil
+}
+
+// 在 agent 的 goroutine 中调用，认证完成后才读取下一个消息
+func (a *agent) auth(msg interface{}) error {
+	if a.authenticated() {
+		return errors.New("already authenticated")
+	}
+	if a.gate.AuthChanRPC == nil {
+		return errors.New("auth not supported")
+	}
+	ret, err := a.gate.AuthChanRPC.Call1("Auth", msg, a)
+	if err != nil {
+		return err
+	}
+	switch ret := ret.(type) {
+	case error:
+		return ret
+	case string:
+		a.userID = ret
+	default:
+		return fmt.Errorf("invalid auth result %v", ret)
+	}
+	atomic.StoreInt32(&a.authed, 1)
+	if a.gate.resumable() {
+		token, err := a.gate.addResume(a)
+		if err != nil {
+			log.Error("resume token error: %v", err)
+		} else {
+			// 凭证不计入序号，之后发送的消息从 1 开始编号
+			a.mu.Lock()
+			err = a.write(a.conn, a.gate.ResumeInfo(token))
+			a.resume = newResumeBuffer(token, a.gate.ResumeBuffer)
+			a.mu.Unlock()
+			if err != nil {
+				log.Error("write resume token error: %v", err)
+			}
+		}
+	}
+	if a.gate.AgentChanRPC != nil {
+		a.gate.AgentChanRPC.Go("AuthAgent", a, a.userID)
+	}
+	return nil
+}
+
+func (a *agent) authenticated() bool {
+	return atomic.LoadInt32(&a.authed) == 1
+}
+
+// 空闲时发送心跳，超时后直接销毁连接，对端已经断开时 Close 可能一直等待未发送完的数据；
+// 每次检查时读取当前的 IdleTimeout 和 HeartbeatInterval
+func (a *agent) keepalive(conn network.Conn, lastRead *int64, done chan struct{}) {
+	tick := keepaliveTick(a.gate.limit())
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	var lastPing time.Time
+	for {
+		select {
+		case <-done:
+			return
+		case now := <-ticker.C:
+			limits := a.gate.limit()
+			if t := keepaliveTick(limits); t != tick {
+				tick = t
+				ticker.Reset(tick)
+			}
+			idleTimeout, interval := limits.IdleTimeout, limits.HeartbeatInterval
+			idle := now.Sub(time.Unix(0, atomic.LoadInt64(lastRead)))
+			if idleTimeout > 0 && idle >= idleTimeout {
+				log.Debug("idle timeout: %v", conn.RemoteAddr())
+				a.reason.Store("idle timeout")
+				conn.Destroy()
+				return
+			}
+			if interval > 0 && a.gate.PingMsg != nil && a.gate.Processor != nil && idle >= interval && now.Sub(lastPing) >= interval {
+				if err := a.write(conn, a.gate.PingMsg); err != nil {
+					log.Debug("write ping error: %v", err)
+				}
+				lastPing = now
+			}
+		}
+	}
+}
+
+// 检查空闲的间隔，都不启用时每秒检查一次是否修改了设置
+func keepaliveTick(limits *Limits) time.Duration {
+	tick := limits.HeartbeatInterval
+	if limits.IdleTimeout > 0 && (tick <= 0 || limits.IdleTimeout/4 < tick) {
+		tick = limits.IdleTimeout / 4
+	}
+	if tick <= 0 {
+		tick = time.Second
+	}
+	return tick
+}
+
+// 新连接恢复会话，重发 seq 之后的消息
+func (a *agent) attach(conn network.Conn, seq uint64) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed || a.resume == nil {
+		return errors.New("session closed")
+	}
+	msgs, err := a.resume.since(seq)
+	if err != nil {
+		return err
+	}
+	if a.grace != nil {
+		a.grace.Stop()
+		a.grace = nil
+	}
+	if a.online && a.conn != conn {
+		// 旧连接可能还没有发现对端已经断开
+		a.conn.Destroy()
+	}
+	a.conn = conn
+	a.online = true
+	a.reason.Store("")
+
+	if err := a.write(conn, a.gate.ResumeResult(nil)); err != nil {
+		return err
+	}
+	for _, data := range msgs {
+		if err := conn.WriteMsg(data...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *agent) ack(seq uint64) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.resume != nil {
+		a.resume.ack(seq)
+	}
+}
+
+func (a *agent) OnClose() {
+	if !a.counted {
+		return
+	}
+	atomic.AddInt32(&a.gate.conns, -1)
+	a.gate.ips.remove(a.ip)
+	if a.resumedTo != nil {
+		a.resumedTo.disconnected(a.own)
+		return
+	}
+	a.disconnected(a.own)
+}
+
+// conn 断开，可以恢复时等待 ResumeGrace，否则通知 CloseAgent
+func (a *agent) disconnected(conn network.Conn) {
+	a.mu.Lock()
+	if a.conn != conn || a.closed {
+		// 已经换成了新的连接
+		a.mu.Unlock()
+		return
+	}
+	a.online = false
+	if grace := a.gate.limit().ResumeGrace; a.resume != nil && grace > 0 && atomic.LoadInt32(&a.final) == 0 {
+		a.grace = time.AfterFunc(grace, func() {
+			a.expire(conn, "resume timeout")
+		})
+		a.mu.Unlock()
+		log.Debug("%v disconnected, wait %v for resume", a.userID, grace)
+		return
+	}
+	a.closed = true
+	a.mu.Unlock()
+	a.closeAgent()
+}
+
+// 等待恢复超时，conn 为断开时的连接，之后恢复过会话时不处理
+func (a *agent) expire(conn network.Conn, reason string) {
+	a.mu.Lock()
+	if a.conn != conn || a.online || a.closed {
+		a.mu.Unlock()
+		return
+	}
+	a.closed = true
+	a.mu.Unlock()
+	if r, _ := a.reason.Load().(string); r == "" {
+		a.reason.Store(reason)
+	}
+	a.closeAgent()
+}
+
+// 通过 CloseAgent 的第二个参数通知关闭原因
+func (a *agent) closeAgent() {
+	a.mu.Lock()
+	if a.resume != nil {
+		a.gate.removeResume(a.resume.token)
+	}
+	a.mu.Unlock()
+	if a.gate.AgentChanRPC != nil {
+		reason, _ := a.reason.Load().(string)
+		err := a.gate.AgentChanRPC.Call0("CloseAgent", a, reason)
+		if err != nil {
+			log.Error("chanrpc error: %v", err)
+		}
+	}
+}
+
+func (a *agent) WriteMsg(msg interface{}) {
+	if a.gate.Processor != nil {
+		data, err := a.gate.Processor.Marshal(msg)
+		if err != nil {
+			log.Error("marshal message %v error: %v", reflect.TypeOf(msg), err)
+			return
+		}
+		if err := a.writeData(data); err != nil {
+			log.Error("write message %v error: %v", reflect.TypeOf(msg), err)
+		}
+	}
+}
+
+// 写入已经编码的消息，广播时多个连接共用同一份数据
+func (a *agent) WriteData(data [][]byte) {
+	if err := a.writeData(data); err != nil {
+		log.Error("write message error: %v", err)
+	}
+}
+
+// 可以恢复的会话先记录消息，断开期间只记录
+func (a *agent) writeData(data [][]byte) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.resume != nil {
+		a.resume.push(data)
+	}
+	if !a.online {
+		return nil
+	}
+	return a.conn.WriteMsg(data...)
+}
+
+// 直接写入 conn，不计入序号
+func (a *agent) write(conn network.Conn, msg interface{}) error {
+	data, err := a.gate.Processor.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMsg(data...)
+}
+
+func (a *agent) current() network.Conn {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.conn
+}
+
+func (a *agent) LocalAddr() net.Addr {
+	return a.current().LocalAddr()
+}
+
+func (a *agent) RemoteAddr() net.Addr {
+	return a.current().RemoteAddr()
+}
+
+// 主动关闭的会话不再恢复，等待恢复期间直接通知 CloseAgent
+func (a *agent) Close() {
+	a.shutdown(false)
+}
+
+func (a *agent) Destroy() {
+	a.shutdown(true)
+}
+
+func (a *agent) shutdown(destroy bool) {
+	atomic.StoreInt32(&a.final, 1)
+	a.mu.Lock()
+	conn, online := a.conn, a.online
+	a.mu.Unlock()
+	if !online {
+		// 可能在 AgentChanRPC 的 goroutine 中调用，不能同步通知
+		go a.expire(conn, "closed")
+		return
+	}
+	if destroy {
+		conn.Destroy()
+	} else {
+		conn.Close()
+	}
+}
+
+func (a *agent) UserData() interface{} {
+	return a.userData
+}
+
+func (a *agent) SetUserData(data interface{}) {
+	a.userData = data
+}
@@ -0,0 +1,377 @@
+package gateway
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/name5566/leaf/chanrpc"
+	"github.com/name5566/leaf/network/json"
+
+	"GoLeafServer/leafserver/src/server/msg"
+)
+
+// net.Pipe 上的连接，每个消息前为 4 字节长度
+type pipeConn struct {
+	net.Conn
+	mu sync.Mutex
+}
+
+func (c *pipeConn) ReadMsg() ([]byte, error) {
+	var n uint32
+	if err := binary.Read(c.Conn, binary.BigEndian, &n); err != nil {
+		return nil, err
+	}
+	data := make([]byte, n)
+	_, err := io.ReadFull(c.Conn, data)
+	return data, err
+}
+
+func (c *pipeConn) WriteMsg(args ...[]byte) error {
+	data := make([]byte, 4)
+	for _, b := range args {
+		data = append(data, b...)
+	}
+	binary.BigEndian.PutUint32(data, uint32(len(data)-4))
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.Conn.Write(data)
+	return err
+}
+
+func (c *pipeConn) Close()   { c.Conn.Close() }
+func (c *pipeConn) Destroy() { c.Conn.Close() }
+
+// AgentChanRPC 和 AuthChanRPC 收到的调用
+type event struct {
+	id   string
+	args []interface{}
+}
+
+type testGate struct {
+	*Gate
+	t      *testing.T
+	events chan event
+	routed chan []interface{}
+}
+
+func newTestGate(t *testing.T) *testGate {
+	g := &testGate{t: t, events: make(chan event, 100), routed: make(chan []interface{}, 100)}
+
+	processor := json.NewProcessor()
+	for _, m := range []interface{}{&msg.Hello{}, &msg.Auth{}, &msg.Ping{}, &msg.Pong{},
+		&msg.ResumeInfo{}, &msg.Resume{}, &msg.ResumeResult{}, &msg.Ack{}} {
+		processor.Register(m)
+	}
+	processor.SetHandler(&msg.Hello{}, func(args []interface{}) {
+		g.routed <- args
+	})
+
+	rpc := chanrpc.NewServer(10)
+	for _, id := range []string{"NewAgent", "CloseAgent", "AuthAgent", "ResumeAgent"} {
+		id := id
+		rpc.Register(id, func(args []interface{}) {
+			g.events <- event{id, args}
+		})
+	}
+	rpc.Register("Auth", func(args []interface{}) interface{} {
+		if args[0].(*msg.Auth).Token != "ok" {
+			return errors.New("bad token")
+		}
+		return "u1"
+	})
+	go func() {
+		for ci := range rpc.ChanCall {
+			rpc.Exec(ci)
+		}
+	}()
+
+	g.Gate = &Gate{
+		Processor:    processor,
+		AgentChanRPC: rpc,
+		AuthMsg:      &msg.Auth{},
+		AuthChanRPC:  rpc,
+		PingMsg:      &msg.Ping{},
+		PongMsg:      &msg.Pong{},
+		ResumeBuffer: 8,
+		ResumeInfo: func(token string) interface{} {
+			return &msg.ResumeInfo{Token: token}
+		},
+		ResumeResult: func(err error) interface{} {
+			if err != nil {
+				return &msg.ResumeResult{Error: err.Error()}
+			}
+			return &msg.ResumeResult{}
+		},
+		ParseResume: func(m interface{}) (string, uint64, bool) {
+			if r, ok := m.(*msg.Resume); ok {
+				return r.Token, r.Seq, true
+			}
+			return "", 0, false
+		},
+		ParseAck: func(m interface{}) (uint64, bool) {
+			if r, ok := m.(*msg.Ack); ok {
+				return r.Seq, true
+			}
+			return 0, false
+		},
+	}
+	return g
+}
+
+// 与 leaf 的 TCPServer 相同，Run 返回后关闭连接再调用 OnClose
+func (g *testGate) dial() *testClient {
+	server, client := net.Pipe()
+	c := &testClient{t: g.t, conn: &pipeConn{Conn: client}, processor: g.Processor, msgs: make(chan interface{}, 100)}
+	go func() {
+		defer close(c.msgs)
+		for {
+			data, err := c.conn.ReadMsg()
+			if err != nil {
+				return
+			}
+			m, err := c.processor.Unmarshal(data)
+			if err != nil {
+				g.t.Error(err)
+				return
+			}
+			c.msgs <- m
+		}
+	}()
+	conn := &pipeConn{Conn: server}
+	a := g.newAgent(conn)
+	go func() {
+		a.Run()
+		conn.Close()
+		a.OnClose()
+	}()
+	return c
+}
+
+// 等待 id 的调用，跳过其他调用
+func (g *testGate) wait(id string) []interface{} {
+	g.t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case e := <-g.events:
+			if e.id == id {
+				return e.args
+			}
+		case <-timeout:
+			g.t.Fatalf("no %v", id)
+		}
+	}
+}
+
+func (g *testGate) waitRouted() []interface{} {
+	g.t.Helper()
+	select {
+	case args := <-g.routed:
+		return args
+	case <-time.After(2 * time.Second):
+		g.t.Fatal("nothing routed")
+	}
+	return nil
+}
+
+type testClient struct {
+	t         *testing.T
+	conn      *pipeConn
+	processor interface {
+		Marshal(msg interface{}) ([][]byte, error)
+		Unmarshal(data []byte) (interface{}, error)
+	}
+	msgs chan interface{}
+}
+
+func (c *testClient) send(m interface{}) {
+	data, err := c.processor.Marshal(m)
+	if err != nil {
+		c.t.Fatal(err)
+	}
+	if err := c.conn.WriteMsg(data...); err != nil {
+		c.t.Fatalf("send %T: %v", m, err)
+	}
+}
+
+func (c *testClient) recv() interface{} {
+	c.t.Helper()
+	select {
+	case m, ok := <-c.msgs:
+		if !ok {
+			c.t.Fatal("connection closed")
+		}
+		return m
+	case <-time.After(2 * time.Second):
+		c.t.Fatal("no message")
+	}
+	return nil
+}
+
+// 等待服务端关闭连接，期间收到的消息丢弃
+func (c *testClient) waitClosed() {
+	c.t.Helper()
+	timeout := time.After(2 * time.Second)
+	for {
+		select {
+		case _, ok := <-c.msgs:
+			if !ok {
+				return
+			}
+		case <-timeout:
+			c.t.Fatal("connection not closed")
+		}
+	}
+}
+
+func TestHandshake(t *testing.T) {
+	g := newTestGate(t)
+	g.AuthRequired = true
+	g.Limits.HandshakeTimeout = 100 * time.Millisecond
+
+	//认证前的消息不路由，超时后关闭
+	c := g.dial()
+	c.send(&msg.Hello{Name: "early"})
+	c.waitClosed()
+	if reason := g.wait("CloseAgent")[1]; reason != "handshake timeout" {
+		t.Errorf("got close reason %q", reason)
+	}
+	if len(g.routed) != 0 {
+		t.Error("message routed before auth")
+	}
+
+	c = g.dial()
+	c.send(&msg.Auth{Token: "ok"})
+	if userID := g.wait("AuthAgent")[1]; userID != "u1" {
+		t.Errorf("got user %v", userID)
+	}
+	time.Sleep(150 * time.Millisecond)
+	c.send(&msg.Hello{Name: "after"})
+	if m := g.waitRouted()[0].(*msg.Hello); m.Name != "after" {
+		t.Errorf("got %+v", m)
+	}
+	c.conn.Close()
+
+	c = g.dial()
+	c.send(&msg.Auth{Token: "bad"})
+	c.waitClosed()
+}
+
+func TestIdleTimeout(t *testing.T) {
+	g := newTestGate(t)
+	g.Limits.MsgRate = 1
+	g.Limits.MsgBurst = 1
+	g.Limits.IdleTimeout = 200 * time.Millisecond
+	g.Limits.HeartbeatInterval = 50 * time.Millisecond
+
+	c := g.dial()
+	if _, ok := c.recv().(*msg.Ping); !ok {
+		t.Fatal("expected ping")
+	}
+	//心跳不计入限制
+	for i := 0; i < 3; i++ {
+		c.send(&msg.Ping{})
+		for {
+			if _, ok := c.recv().(*msg.Pong); ok {
+				break
+			}
+		}
+	}
+	c.waitClosed()
+	if reason := g.wait("CloseAgent")[1]; reason != "idle timeout" {
+		t.Errorf("got close reason %q", reason)
+	}
+}
+
+func TestResume(t *testing.T) {
+	g := newTestGate(t)
+	g.Limits.ResumeGrace = time.Second
+
+	c := g.dial()
+	c.send(&msg.Auth{Token: "ok"})
+	info, ok := c.recv().(*msg.ResumeInfo)
+	if !ok || info.Token == "" {
+		t.Fatalf("got %+v", info)
+	}
+	a := g.wait("AuthAgent")[0].(*agent)
+	for _, name := range []string{"1", "2", "3"} {
+		a.WriteMsg(&msg.Hello{Name: name})
+		if m, _ := c.recv().(*msg.Hello); m == nil || m.Name != name {
+			t.Fatalf("got %+v, want %v", m, name)
+		}
+	}
+	//Pong 不计入序号
+	c.send(&msg.Ping{})
+	if _, ok := c.recv().(*msg.Pong); !ok {
+		t.Fatal("expected pong")
+	}
+	c.send(&msg.Ack{Seq: 1})
+	c.conn.Close()
+	a.WriteMsg(&msg.Hello{Name: "4"})
+
+	//凭证不存在时失败
+	bad := g.dial()
+	bad.send(&msg.Resume{Token: "x", Seq: 3})
+	if r, _ := bad.recv().(*msg.ResumeResult); r == nil || r.Error != "session not found" {
+		t.Errorf("got %+v", r)
+	}
+	bad.waitClosed()
+
+	c = g.dial()
+	c.send(&msg.Resume{Token: info.Token, Seq: 3})
+	if r, _ := c.recv().(*msg.ResumeResult); r == nil || r.Error != "" {
+		t.Fatalf("got %+v", r)
+	}
+	if m, _ := c.recv().(*msg.Hello); m == nil || m.Name != "4" {
+		t.Errorf("got %+v, want 4", m)
+	}
+	if resumed := g.wait("ResumeAgent")[0]; resumed != a {
+		t.Errorf("got %v, want original agent", resumed)
+	}
+
+	//恢复后新连接上的消息属于原来的 agent
+	a.WriteMsg(&msg.Hello{Name: "5"})
+	if m, _ := c.recv().(*msg.Hello); m == nil || m.Name != "5" {
+		t.Errorf("got %+v, want 5", m)
+	}
+	c.send(&msg.Hello{Name: "from new conn"})
+	if args := g.waitRouted(); args[1] != a {
+		t.Errorf("routed to %v", args[1])
+	}
+	c.conn.Close()
+}
+
+func TestConnLimit(t *testing.T) {
+	for _, limits := range []Limits{{MaxConnNum: 1}, {MaxConnPerIP: 1}} {
+		g := newTestGate(t)
+		g.Limits = limits
+		c := g.dial()
+		a := g.wait("NewAgent")[0]
+
+		//超出限制的连接直接关闭，不通知 NewAgent 和 CloseAgent
+		over := g.dial()
+		over.waitClosed()
+		time.Sleep(50 * time.Millisecond)
+		c.conn.Close()
+		for closed := false; !closed; {
+			select {
+			case e := <-g.events:
+				if e.args[0] != a {
+					t.Errorf("%+v: got %v of rejected connection", limits, e.id)
+				}
+				closed = e.id == "CloseAgent"
+			case <-time.After(2 * time.Second):
+				t.Fatalf("%+v: no CloseAgent", limits)
+			}
+		}
+		if n := atomic.LoadInt32(&g.conns); n != 0 {
+			t.Errorf("%+v: %v connections counted", limits, n)
+		}
+	}
+}
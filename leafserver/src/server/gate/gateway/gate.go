@@ -0,0 +1,216 @@
+package gateway
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"github.com/name5566/leaf/chanrpc"
+	"github.com/name5566/leaf/log"
+	"github.com/name5566/leaf/network"
+)
+
+// 与 leaf 的 gate.Gate 相同，增加了认证、心跳、限流和断线后恢复会话
+type Gate struct {
+	PendingWriteNum int
+	MaxMsgLen       uint32
+	Processor       network.Processor
+	AgentChanRPC    *chanrpc.Server
+	// Processor 中注册的消息名，用于检查 Limits.MsgTypeRates，为空时不检查
+	MsgNames []string
+
+	// websocket
+	WSAddr      string
+	HTTPTimeout time.Duration
+	CertFile    string
+	KeyFile     string
+
+	// tcp
+	TCPAddr      string
+	LenMsgLen    int
+	LittleEndian bool
+
+	// handshake
+	// 收到 AuthMsg 类型的消息时同步调用 AuthChanRPC 的 "Auth"，参数为消息和 agent，
+	// 返回用户 id 表示认证成功，返回 error 时关闭连接
+	AuthMsg     interface{}
+	AuthChanRPC *chanrpc.Server
+	// 为 true 时认证前只路由 HandshakeMsgs 中的消息，Limits.HandshakeTimeout 内没有认证的连接会被关闭
+	AuthRequired  bool
+	HandshakeMsgs []string
+
+	// heartbeat
+	// 收到 PingMsg 时回复 PongMsg，两者都不会路由
+	PingMsg interface{}
+	PongMsg interface{}
+
+	// 启动时的限制，运行时用 SetLimits 修改
+	Limits Limits
+
+	// resume
+	// 认证后发送 ResumeInfo(凭证)，之后发送的消息从 1 开始编号，断线后会话保留 Limits.ResumeGrace，最多保留 ResumeBuffer 个没有确认的消息；
+	// 客户端用 Ack 确认收到的消息数，在新连接上发送 Resume 带上凭证和收到的消息数，回复 ResumeResult 后按顺序重发没有收到的消息
+	ResumeBuffer int
+	ResumeInfo   func(token string) interface{}
+	ResumeResult func(err error) interface{}
+	ParseResume  func(msg interface{}) (token string, seq uint64, ok bool)
+	ParseAck     func(msg interface{}) (seq uint64, ok bool)
+
+	handshakeMsgs map[string]bool
+	limits        atomic.Value // *Limits
+	conns         int32
+	ips           ipConns
+	stats         limitStats
+	resumes       resumes
+}
+
+// 可以在运行时修改的限制，每次使用时读取，修改后对已有的连接也生效
+type Limits struct {
+	// 连接总数
+	MaxConnNum int
+	// 认证期限，见 AuthRequired
+	HandshakeTimeout time.Duration
+
+	// heartbeat
+	// 空闲超过 HeartbeatInterval 时发送 PingMsg，超过 IdleTimeout 时断开连接，
+	// CloseAgent 的第二个参数为 "idle timeout"，为 0 时不启用
+	IdleTimeout       time.Duration
+	HeartbeatInterval time.Duration
+
+	// rate limit
+	// 每个连接每秒的消息数和字节数，每种消息每秒的数量，每个 ip 的连接数，为 0 时不限制
+	MaxConnPerIP    int
+	MsgRate         float64
+	MsgBurst        int
+	ByteRate        int
+	ByteBurst       int
+	MsgTypeRates    map[string]float64
+	RateLimitAction string
+
+	// 断线后会话保留的时间，为 0 时不能恢复
+	ResumeGrace time.Duration
+}
+
+// 替换当前的限制，可以在任何 goroutine 中调用，之后 l 不能修改
+func (gate *Gate) SetLimits(l Limits) {
+	gate.limits.Store(&l)
+}
+
+// 检查 MsgTypeRates 中的消息名都已经注册
+func (gate *Gate) CheckLimits(l Limits) error {
+	if len(gate.MsgNames) == 0 {
+		return nil
+	}
+	names := make(map[string]bool)
+	for _, name := range gate.MsgNames {
+		names[name] = true
+	}
+	for name := range l.MsgTypeRates {
+		if !names[name] {
+			return fmt.Errorf("MsgTypeRates: message %v not registered", name)
+		}
+	}
+	return nil
+}
+
+func (gate *Gate) limit() *Limits {
+	if l, ok := gate.limits.Load().(*Limits); ok {
+		return l
+	}
+	return &gate.Limits
+}
+
+func (gate *Gate) Run(closeSig chan bool) {
+	gate.handshakeMsgs = make(map[string]bool)
+	for _, name := range gate.HandshakeMsgs {
+		gate.handshakeMsgs[name] = true
+	}
+
+	var wsServer *network.WSServer
+	if gate.WSAddr != "" {
+		wsServer = new(network.WSServer)
+		wsServer.Addr = gate.WSAddr
+		// 连接总数由 newAgent 按当前的 MaxConnNum 限制
+		wsServer.MaxConnNum = math.MaxInt32
+		wsServer.PendingWriteNum = gate.PendingWriteNum
+		wsServer.MaxMsgLen = gate.MaxMsgLen
+		wsServer.HTTPTimeout = gate.HTTPTimeout
+		wsServer.CertFile = gate.CertFile
+		wsServer.KeyFile = gate.KeyFile
+		wsServer.NewAgent = func(conn *network.WSConn) network.Agent {
+			return gate.newAgent(conn)
+		}
+	}
+
+	var tcpServer *network.TCPServer
+	if gate.TCPAddr != "" {
+		tcpServer = new(network.TCPServer)
+		tcpServer.Addr = gate.TCPAddr
+		tcpServer.MaxConnNum = math.MaxInt32
+		tcpServer.PendingWriteNum = gate.PendingWriteNum
+		tcpServer.LenMsgLen = gate.LenMsgLen
+		tcpServer.MaxMsgLen = gate.MaxMsgLen
+		tcpServer.LittleEndian = gate.LittleEndian
+		tcpServer.NewAgent = func(conn *network.TCPConn) network.Agent {
+			return gate.newAgent(conn)
+		}
+	}
+
+	if wsServer != nil {
+		wsServer.Start()
+	}
+	if tcpServer != nil {
+		tcpServer.Start()
+	}
+	<-closeSig
+	if wsServer != nil {
+		wsServer.Close()
+	}
+	if tcpServer != nil {
+		tcpServer.Close()
+	}
+}
+
+func (gate *Gate) OnDestroy() {}
+
+// 超出限制的统计
+func (gate *Gate) LimitStats() string {
+	return gate.stats.String()
+}
+
+// 超出连接数限制的连接直接关闭，不通知 AgentChanRPC
+func (gate *Gate) newAgent(conn network.Conn) *agent {
+	a := &agent{gate: gate, own: conn, conn: conn, online: true, ip: host(conn.RemoteAddr())}
+	limits := gate.limit()
+	if n := atomic.AddInt32(&gate.conns, 1); limits.MaxConnNum > 0 && int(n) > limits.MaxConnNum {
+		atomic.AddInt32(&gate.conns, -1)
+		gate.stats.add("conn num", LimitDisconnect)
+		log.Debug("too many connections, close %v", conn.RemoteAddr())
+		conn.Close()
+		return a
+	}
+	if !gate.ips.add(a.ip, limits.MaxConnPerIP) {
+		atomic.AddInt32(&gate.conns, -1)
+		gate.stats.add("conn per ip", LimitDisconnect)
+		log.Debug("too many connections from %v", a.ip)
+		conn.Close()
+		return a
+	}
+	a.counted = true
+	if gate.AgentChanRPC != nil {
+		gate.AgentChanRPC.Go("NewAgent", a)
+	}
+	return a
+}
+
+func (gate *Gate) isMsg(msg interface{}, m interface{}) bool {
+	return m != nil && reflect.TypeOf(msg) == reflect.TypeOf(m)
+}
+
+// 认证前是否可以路由
+func (gate *Gate) isHandshakeMsg(msg interface{}) bool {
+	t := reflect.TypeOf(msg)
+	return t != nil && t.Kind() == reflect.Ptr && gate.handshakeMsgs[t.Elem().Name()]
+}
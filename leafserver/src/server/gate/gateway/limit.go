@@ -0,0 +1,167 @@
+package gateway
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 超出限制时的处理
+const (
+	LimitDrop       = "drop"       // 丢弃消息
+	LimitWarn       = "warn"       // 记录日志，消息照常路由
+	LimitDisconnect = "disconnect" // 断开连接
+)
+
+// 令牌桶，每秒补充 rate 个令牌，最多 burst 个
+type bucket struct {
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func newBucket(rate float64, burst float64, now time.Time) *bucket {
+	if burst < rate {
+		burst = rate
+	}
+	if burst < 1 {
+		burst = 1
+	}
+	return &bucket{rate: rate, burst: burst, tokens: burst, last: now}
+}
+
+func (b *bucket) allow(n float64, now time.Time) bool {
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.burst {
+		b.tokens = b.burst
+	}
+	b.last = now
+	if b.tokens < n {
+		return false
+	}
+	b.tokens -= n
+	return true
+}
+
+// 单个连接的限制，只在 agent 的 goroutine 中使用
+type limiter struct {
+	limits *Limits // 创建令牌桶时的限制
+	msgs   *bucket
+	bytes  *bucket
+	types  map[string]*bucket
+}
+
+func newLimiter(limits *Limits, now time.Time) *limiter {
+	l := &limiter{limits: limits, types: make(map[string]*bucket)}
+	if limits.MsgRate > 0 {
+		l.msgs = newBucket(limits.MsgRate, float64(limits.MsgBurst), now)
+	}
+	if limits.ByteRate > 0 {
+		l.bytes = newBucket(float64(limits.ByteRate), float64(limits.ByteBurst), now)
+	}
+	return l
+}
+
+// 返回超出的限制，没有超出时为空；限制修改过时按新的限制重新开始
+func (gate *Gate) checkLimit(l *limiter, msg interface{}, size int, now time.Time) string {
+	if limits := gate.limit(); l.limits != limits {
+		*l = *newLimiter(limits, now)
+	}
+	if l.msgs != nil && !l.msgs.allow(1, now) {
+		return "msg rate"
+	}
+	if l.bytes != nil && !l.bytes.allow(float64(size), now) {
+		return "byte rate"
+	}
+	t := reflect.TypeOf(msg)
+	if t == nil || t.Kind() != reflect.Ptr {
+		return ""
+	}
+	name := t.Elem().Name()
+	rate, ok := l.limits.MsgTypeRates[name]
+	if !ok {
+		return ""
+	}
+	b := l.types[name]
+	if b == nil {
+		b = newBucket(rate, rate, now)
+		l.types[name] = b
+	}
+	if !b.allow(1, now) {
+		return name + " rate"
+	}
+	return ""
+}
+
+// 每个 ip 的连接数
+type ipConns struct {
+	sync.Mutex
+	conns map[string]int
+}
+
+func host(addr net.Addr) string {
+	if addr == nil {
+		return ""
+	}
+	h, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return h
+}
+
+// max 为 0 时不限制
+func (c *ipConns) add(ip string, max int) bool {
+	c.Lock()
+	defer c.Unlock()
+	if max > 0 && c.conns[ip] >= max {
+		return false
+	}
+	if c.conns == nil {
+		c.conns = make(map[string]int)
+	}
+	c.conns[ip]++
+	return true
+}
+
+func (c *ipConns) remove(ip string) {
+	c.Lock()
+	defer c.Unlock()
+	if c.conns[ip]--; c.conns[ip] <= 0 {
+		delete(c.conns, ip)
+	}
+}
+
+// 超出限制的次数，按限制和处理方式统计
+type limitStats struct {
+	sync.Mutex
+	counts map[[2]string]uint64
+}
+
+func (s *limitStats) add(limit string, action string) {
+	s.Lock()
+	defer s.Unlock()
+	if s.counts == nil {
+		s.counts = make(map[[2]string]uint64)
+	}
+	s.counts[[2]string{limit, action}]++
+}
+
+func (s *limitStats) String() string {
+	s.Lock()
+	defer s.Unlock()
+	if len(s.counts) == 0 {
+		return "no rate limit exceeded"
+	}
+	lines := make([]string, 0, len(s.counts))
+	for k, n := range s.counts {
+		lines = append(lines, fmt.Sprintf("%v %v: %v", k[0], k[1], n))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "\r\n")
+}
@@ -0,0 +1,102 @@
+package gateway
+
+import (
+	"testing"
+	"time"
+)
+
+type hello struct{}
+type bye struct{}
+
+func TestBucket(t *testing.T) {
+	now := time.Now()
+	b := newBucket(2, 3, now)
+	for i := 0; i < 3; i++ {
+		if !b.allow(1, now) {
+			t.Fatalf("burst %v rejected", i)
+		}
+	}
+	if b.allow(1, now) {
+		t.Error("allowed over burst")
+	}
+	//每秒补充 2 个
+	now = now.Add(500 * time.Millisecond)
+	if !b.allow(1, now) || b.allow(1, now) {
+		t.Error("refill mismatch")
+	}
+	//最多补充到 burst
+	now = now.Add(time.Minute)
+	if !b.allow(3, now) || b.allow(1, now) {
+		t.Error("burst not capped")
+	}
+}
+
+func TestCheckLimit(t *testing.T) {
+	gate := &Gate{Limits: Limits{MsgRate: 1, MsgBurst: 2, MsgTypeRates: map[string]float64{"hello": 1}}}
+	now := time.Now()
+	l := newLimiter(gate.limit(), now)
+	for i, want := range []string{"", "hello rate", "msg rate"} {
+		if got := gate.checkLimit(l, &hello{}, 1, now); got != want {
+			t.Errorf("%v: got %q, want %q", i, got, want)
+		}
+	}
+
+	//修改限制后按新的限制重新开始
+	gate.SetLimits(Limits{ByteRate: 10, ByteBurst: 10})
+	if got := gate.checkLimit(l, &hello{}, 8, now); got != "" {
+		t.Errorf("got %q after SetLimits", got)
+	}
+	if got := gate.checkLimit(l, &bye{}, 8, now); got != "byte rate" {
+		t.Errorf("got %q, want byte rate", got)
+	}
+}
+
+func TestCheckLimits(t *testing.T) {
+	gate := &Gate{MsgNames: []string{"Hello"}}
+	if err := gate.CheckLimits(Limits{MsgTypeRates: map[string]float64{"Hello": 1}}); err != nil {
+		t.Error(err)
+	}
+	if err := gate.CheckLimits(Limits{MsgTypeRates: map[string]float64{"Helo": 1}}); err == nil {
+		t.Error("expected error for unknown message")
+	}
+	//没有消息名时不检查
+	gate.MsgNames = nil
+	if err := gate.CheckLimits(Limits{MsgTypeRates: map[string]float64{"Helo": 1}}); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestIPConns(t *testing.T) {
+	var c ipConns
+	if !c.add("a", 2) || !c.add("a", 2) || c.add("a", 2) {
+		t.Fatal("max mismatch")
+	}
+	if !c.add("b", 2) || !c.add("c", 0) {
+		t.Error("other ip rejected")
+	}
+	c.remove("a")
+	if !c.add("a", 2) {
+		t.Error("not released")
+	}
+	c.remove("c")
+	if _, ok := c.conns["c"]; ok {
+		t.Error("empty ip not deleted")
+	}
+}
+
+func TestKeepaliveTick(t *testing.T) {
+	cases := []struct {
+		idle, interval, want time.Duration
+	}{
+		{0, 0, time.Second},
+		{0, 30 * time.Second, 30 * time.Second},
+		{40 * time.Second, 0, 10 * time.Second},
+		{90 * time.Second, 30 * time.Second, 22500 * time.Millisecond},
+		{90 * time.Second, 10 * time.Second, 10 * time.Second},
+	}
+	for _, c := range cases {
+		if got := keepaliveTick(&Limits{IdleTimeout: c.idle, HeartbeatInterval: c.interval}); got != c.want {
+			t.Errorf("%v %v: got %v, want %v", c.idle, c.interval, got, c.want)
+		}
+	}
+}
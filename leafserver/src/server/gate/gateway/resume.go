@@ -0,0 +1,136 @@
+package gateway
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/name5566/leaf/log"
+	"github.com/name5566/leaf/network"
+)
+
+// 可以恢复的会话，按凭证索引
+type resumes struct {
+	sync.Mutex
+	agents map[string]*agent
+}
+
+// 会话发出的消息，最多保留 max 个没有确认的消息
+type resumeBuffer struct {
+	token string
+	max   int
+	sent  uint64     // 最后一个消息的序号
+	msgs  [][][]byte // 序号为 sent-len(msgs)+1 到 sent
+}
+
+func newResumeBuffer(token string, max int) *resumeBuffer {
+	return &resumeBuffer{token: token, max: max}
+}
+
+func (b *resumeBuffer) first() uint64 {
+	return b.sent - uint64(len(b.msgs)) + 1
+}
+
+func (b *resumeBuffer) push(data [][]byte) {
+	b.sent++
+	b.msgs = append(b.msgs, data)
+	if len(b.msgs) > b.max {
+		b.msgs[0] = nil
+		b.msgs = b.msgs[1:]
+	}
+}
+
+// 客户端已经收到了 seq 及之前的消息
+func (b *resumeBuffer) ack(seq uint64) {
+	if seq > b.sent {
+		seq = b.sent
+	}
+	if first := b.first(); seq >= first {
+		n := seq - first + 1
+		for i := uint64(0); i < n; i++ {
+			b.msgs[i] = nil
+		}
+		b.msgs = b.msgs[n:]
+	}
+}
+
+// 客户端收到 seq 之后需要重发的消息
+func (b *resumeBuffer) since(seq uint64) ([][][]byte, error) {
+	if seq > b.sent {
+		return nil, errors.New("invalid seq")
+	}
+	if seq+1 < b.first() {
+		return nil, errors.New("messages lost")
+	}
+	b.ack(seq)
+	return b.msgs, nil
+}
+
+func (gate *Gate) resumable() bool {
+	return gate.limit().ResumeGrace > 0 && gate.ResumeInfo != nil && gate.ResumeResult != nil && gate.ParseResume != nil
+}
+
+func (gate *Gate) parseResume(msg interface{}) (string, uint64, bool) {
+	if !gate.resumable() {
+		return "", 0, false
+	}
+	return gate.ParseResume(msg)
+}
+
+func (gate *Gate) parseAck(msg interface{}) (uint64, bool) {
+	if gate.ParseAck == nil {
+		return 0, false
+	}
+	return gate.ParseAck(msg)
+}
+
+func (gate *Gate) addResume(a *agent) (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(b)
+
+	gate.resumes.Lock()
+	defer gate.resumes.Unlock()
+	if gate.resumes.agents == nil {
+		gate.resumes.agents = make(map[string]*agent)
+	}
+	gate.resumes.agents[token] = a
+	return token, nil
+}
+
+func (gate *Gate) removeResume(token string) {
+	gate.resumes.Lock()
+	defer gate.resumes.Unlock()
+	delete(gate.resumes.agents, token)
+}
+
+// 新连接 b 通过凭证恢复会话，成功后 b 不再使用，AgentChanRPC 收到 b 的 CloseAgent 和原会话的 ResumeAgent
+func (gate *Gate) resumeSession(b *agent, conn network.Conn, token string, seq uint64) (*agent, error) {
+	if b.authenticated() {
+		return nil, errors.New("already authenticated")
+	}
+	gate.resumes.Lock()
+	a := gate.resumes.agents[token]
+	gate.resumes.Unlock()
+	if a == nil {
+		return nil, errors.New("session not found")
+	}
+	if err := a.attach(conn, seq); err != nil {
+		return nil, err
+	}
+	log.Debug("%v resumed from %v", a.userID, conn.RemoteAddr())
+
+	b.mu.Lock()
+	b.closed = true
+	b.mu.Unlock()
+	if gate.AgentChanRPC != nil {
+		if err := gate.AgentChanRPC.Call0("CloseAgent", b, "resumed"); err != nil {
+			log.Error("chanrpc error: %v", err)
+		}
+		gate.AgentChanRPC.Go("ResumeAgent", a)
+	}
+	return a, nil
+}
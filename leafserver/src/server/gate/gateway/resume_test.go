@@ -0,0 +1,52 @@
+package gateway
+
+import (
+	"testing"
+)
+
+func seqs(msgs [][][]byte) []byte {
+	var s []byte
+	for _, m := range msgs {
+		s = append(s, m[0][0])
+	}
+	return s
+}
+
+func TestResumeBuffer(t *testing.T) {
+	b := newResumeBuffer("t", 3)
+	for i := 1; i <= 5; i++ {
+		b.push([][]byte{{byte(i)}})
+	}
+	//超出 max 时丢弃最早的消息
+	if b.sent != 5 || b.first() != 3 || string(seqs(b.msgs)) != "\x03\x04\x05" {
+		t.Fatalf("sent %v, first %v, msgs %v", b.sent, b.first(), seqs(b.msgs))
+	}
+	if _, err := b.since(1); err == nil {
+		t.Error("expected messages lost")
+	}
+	if _, err := b.since(6); err == nil {
+		t.Error("expected invalid seq")
+	}
+	msgs, err := b.since(2)
+	if err != nil || string(seqs(msgs)) != "\x03\x04\x05" {
+		t.Errorf("since 2: %v %v", seqs(msgs), err)
+	}
+
+	b.ack(3)
+	if b.first() != 4 || len(b.msgs) != 2 {
+		t.Errorf("after ack 3: first %v, %v msgs", b.first(), len(b.msgs))
+	}
+	//已经确认过的序号不处理，超出的序号按最后一个处理
+	b.ack(1)
+	b.ack(10)
+	if b.first() != 6 || len(b.msgs) != 0 {
+		t.Errorf("after ack 10: first %v, %v msgs", b.first(), len(b.msgs))
+	}
+	if msgs, err := b.since(5); err != nil || len(msgs) != 0 {
+		t.Errorf("since 5: %v %v", len(msgs), err)
+	}
+	b.push([][]byte{{6}})
+	if msgs, _ := b.since(5); string(seqs(msgs)) != "\x06" {
+		t.Errorf("since 5 after push: %v", seqs(msgs))
+	}
+}
@@ -0,0 +1,9 @@
+package internal
+
+func init() {
+	skeleton.RegisterCommand("ratelimit", "show rate limit counters of the gate", commandRateLimit)
+}
+
+func commandRateLimit(args []interface{}) interface{} {
+	return server.LimitStats()
+}
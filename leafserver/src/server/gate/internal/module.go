@@ -1,19 +1,36 @@
 package internal
 
 import (
+	"github.com/name5566/leaf/log"
+	"github.com/name5566/leaf/module"
+
+	"GoLeafServer/leafserver/src/server/base"
 	"GoLeafServer/leafserver/src/server/conf"
 	"GoLeafServer/leafserver/src/server/game"
+	"GoLeafServer/leafserver/src/server/gate/gateway"
+	"GoLeafServer/leafserver/src/server/login"
 	"GoLeafServer/leafserver/src/server/msg"
-	"github.com/name5566/leaf/gate"
 )
 
+// gate 模块自己的 skeleton 用于控制台命令
+var (
+	skeleton = base.NewSkeleton()
+	ChanRPC  = skeleton.ChanRPCServer
+	server   *gateway.Gate
+)
+
+func init() {
+	skeleton.RegisterChanRPC(conf.ReloadRPC, rpcConfReloaded)
+	conf.Subscribe(ChanRPC)
+}
+
 type Module struct {
-	*gate.Gate
+	*module.Skeleton
 }
 
 func (m *Module) OnInit() {
-	m.Gate = &gate.Gate{
-		MaxConnNum:      conf.Server.MaxConnNum,
+	m.Skeleton = skeleton
+	server = &gateway.Gate{
 		PendingWriteNum: conf.PendingWriteNum,
 		MaxMsgLen:       conf.MaxMsgLen,
 		WSAddr:          conf.Server.WSAddr,
@@ -25,5 +42,87 @@ func (m *Module) OnInit() {
 		LittleEndian:    conf.LittleEndian,
 		Processor:       msg.Processor,
 		AgentChanRPC:    game.ChanRPC,
+		MsgNames:        msg.Names(),
+
+		AuthMsg:       &msg.Auth{},
+		AuthChanRPC:   login.ChanRPC,
+		AuthRequired:  conf.Server.AuthRequired,
+		HandshakeMsgs: conf.Server.HandshakeMsgs,
+
+		PingMsg: &msg.Ping{},
+		PongMsg: &msg.Pong{},
+
+		Limits: limits(conf.Current()),
+
+		ResumeBuffer: conf.ResumeBuffer,
+		ResumeInfo: func(token string) interface{} {
+			return &msg.ResumeInfo{Token: token}
+		},
+		ResumeResult: func(err error) interface{} {
+			if err != nil {
+				return &msg.ResumeResult{Error: err.Error()}
+			}
+			return &msg.ResumeResult{}
+		},
+		ParseResume: func(m interface{}) (string, uint64, bool) {
+			if r, ok := m.(*msg.Resume); ok {
+				return r.Token, r.Seq, true
+			}
+			return "", 0, false
+		},
+		ParseAck: func(m interface{}) (uint64, bool) {
+			if r, ok := m.(*msg.Ack); ok {
+				return r.Seq, true
+			}
+			return 0, false
+		},
+	}
+	if err := server.CheckLimits(server.Limits); err != nil {
+		log.Fatal("gate: %v", err)
 	}
 }
+
+// 配置中可以在运行时修改的限制
+func limits(c *conf.Config) gateway.Limits {
+	return gateway.Limits{
+		MaxConnNum:        c.MaxConnNum,
+		HandshakeTimeout:  c.HandshakeTimeout,
+		IdleTimeout:       c.IdleTimeout,
+		HeartbeatInterval: c.HeartbeatInterval,
+		MaxConnPerIP:      c.MaxConnPerIP,
+		MsgRate:           c.MsgRate,
+		MsgBurst:          c.MsgBurst,
+		ByteRate:          c.ByteRate,
+		ByteBurst:         c.ByteBurst,
+		MsgTypeRates:      c.MsgTypeRates,
+		RateLimitAction:   c.RateLimitAction,
+		ResumeGrace:       c.ResumeGrace,
+	}
+}
+
+// 重新加载配置后替换 gate 的限制，有问题时保留原来的限制
+func rpcConfReloaded(args []interface{}) {
+	l := limits(conf.Current())
+	if err := server.CheckLimits(l); err != nil {
+		log.Error("gate: %v", err)
+		return
+	}
+	server.SetLimits(l)
+}
+
+// gate 和 skeleton 同时运行，skeleton 退出后再关闭 gate
+func (m *Module) Run(closeSig chan bool) {
+	gateCloseSig := make(chan bool, 1)
+	done := make(chan struct{})
+	go func() {
+		server.Run(gateCloseSig)
+		close(done)
+	}()
+	m.Skeleton.Run(closeSig)
+	gateCloseSig <- true
+	<-done
+}
+
+func (m *Module) OnDestroy() {
+	server.OnDestroy()
+}
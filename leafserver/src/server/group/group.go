@@ -0,0 +1,136 @@
+package group
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/name5566/leaf/gate"
+	"github.com/name5566/leaf/log"
+	"github.com/name5566/leaf/network"
+)
+
+// 可以直接写入编码后消息的 agent，广播时只编码一次，gate 的 agent 实现了这个接口
+type dataWriter interface {
+	WriteData(data [][]byte)
+}
+
+// 命名的 agent 分组，例如房间、公会、世界频道，可以在任何 goroutine 中使用
+type Manager struct {
+	Processor network.Processor
+
+	mu     sync.RWMutex
+	groups map[string]map[gate.Agent]struct{}
+	joined map[gate.Agent]map[string]struct{}
+}
+
+func NewManager(processor network.Processor) *Manager {
+	return &Manager{
+		Processor: processor,
+		groups:    make(map[string]map[gate.Agent]struct{}),
+		joined:    make(map[gate.Agent]map[string]struct{}),
+	}
+}
+
+// 加入分组，分组不存在时创建
+func (m *Manager) Join(name string, a gate.Agent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	members := m.groups[name]
+	if members == nil {
+		members = make(map[gate.Agent]struct{})
+		m.groups[name] = members
+	}
+	members[a] = struct{}{}
+	names := m.joined[a]
+	if names == nil {
+		names = make(map[string]struct{})
+		m.joined[a] = names
+	}
+	names[name] = struct{}{}
+}
+
+// 离开分组，分组为空时删除
+func (m *Manager) Leave(name string, a gate.Agent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.leave(name, a)
+}
+
+// 离开全部分组，连接关闭时调用
+func (m *Manager) LeaveAll(a gate.Agent) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name := range m.joined[a] {
+		m.leave(name, a)
+	}
+}
+
+func (m *Manager) leave(name string, a gate.Agent) {
+	if members := m.groups[name]; members != nil {
+		delete(members, a)
+		if len(members) == 0 {
+			delete(m.groups, name)
+		}
+	}
+	if names := m.joined[a]; names != nil {
+		delete(names, name)
+		if len(names) == 0 {
+			delete(m.joined, a)
+		}
+	}
+}
+
+func (m *Manager) Members(name string) []gate.Agent {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	members := make([]gate.Agent, 0, len(m.groups[name]))
+	for a := range m.groups[name] {
+		members = append(members, a)
+	}
+	return members
+}
+
+func (m *Manager) Len(name string) int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.groups[name])
+}
+
+// 全部非空分组的名字，按名字排序
+func (m *Manager) Names() []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	names := make([]string, 0, len(m.groups))
+	for name := range m.groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// 消息只编码一次后发送给分组的全部成员，except 不为 nil 时不发送给它，返回发送的数量
+func (m *Manager) Broadcast(name string, msg interface{}, except gate.Agent) (int, error) {
+	members := m.Members(name)
+	if len(members) == 0 {
+		return 0, nil
+	}
+	data, err := m.Processor.Marshal(msg)
+	if err != nil {
+		log.Error("marshal message %v error: %v", reflect.TypeOf(msg), err)
+		return 0, err
+	}
+	n := 0
+	for _, a := range members {
+		if a == except {
+			continue
+		}
+		if w, ok := a.(dataWriter); ok {
+			w.WriteData(data)
+		} else {
+			a.WriteMsg(msg)
+		}
+		n++
+	}
+	return n, nil
+}
@@ -0,0 +1,68 @@
+package group
+
+import (
+	"net"
+	"reflect"
+	"testing"
+)
+
+type testProcessor struct {
+	marshaled int
+}
+
+func (p *testProcessor) Route(msg interface{}, userData interface{}) error { return nil }
+func (p *testProcessor) Unmarshal(data []byte) (interface{}, error)        { return nil, nil }
+func (p *testProcessor) Marshal(msg interface{}) ([][]byte, error) {
+	p.marshaled++
+	return [][]byte{[]byte(msg.(string))}, nil
+}
+
+type testAgent struct {
+	data [][]byte
+	msgs []interface{}
+}
+
+func (a *testAgent) WriteData(data [][]byte)      { a.data = append(a.data, data...) }
+func (a *testAgent) WriteMsg(msg interface{})     { a.msgs = append(a.msgs, msg) }
+func (a *testAgent) LocalAddr() net.Addr          { return nil }
+func (a *testAgent) RemoteAddr() net.Addr         { return nil }
+func (a *testAgent) Close()                       {}
+func (a *testAgent) Destroy()                     {}
+func (a *testAgent) UserData() interface{}        { return nil }
+func (a *testAgent) SetUserData(data interface{}) {}
+
+// 没有实现 WriteData 的 agent
+type msgAgent struct {
+	*testAgent
+	WriteData struct{}
+}
+
+func TestBroadcast(t *testing.T) {
+	p := new(testProcessor)
+	m := NewManager(p)
+	a, b, c := new(testAgent), new(testAgent), &msgAgent{testAgent: new(testAgent)}
+	m.Join("room", a)
+	m.Join("room", b)
+	m.Join("room", c)
+	m.Join("world", a)
+
+	n, err := m.Broadcast("room", "hi", b)
+	if err != nil || n != 2 || p.marshaled != 1 {
+		t.Fatalf("got %v, %v, marshaled %v", n, err, p.marshaled)
+	}
+	if len(a.data) != 1 || string(a.data[0]) != "hi" || len(b.data) != 0 {
+		t.Errorf("got a %q, b %q", a.data, b.data)
+	}
+	if !reflect.DeepEqual(c.msgs, []interface{}{"hi"}) {
+		t.Errorf("got c %v", c.msgs)
+	}
+
+	m.LeaveAll(a)
+	m.Leave("room", c)
+	if !reflect.DeepEqual(m.Names(), []string{"room"}) || m.Len("room") != 1 || m.Members("room")[0] != b {
+		t.Errorf("got names %v, members %v", m.Names(), m.Members("room"))
+	}
+	if n, _ := m.Broadcast("world", "hi", nil); n != 0 || p.marshaled != 1 {
+		t.Errorf("broadcast to empty group got %v, marshaled %v", n, p.marshaled)
+	}
+}
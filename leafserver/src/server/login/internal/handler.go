@@ -2,6 +2,14 @@ package internal
 
 import (
 	"reflect"
+	"time"
+
+	"github.com/name5566/leaf/gate"
+	"github.com/name5566/leaf/log"
+
+	"GoLeafServer/leafserver/src/server/conf"
+	"GoLeafServer/leafserver/src/server/login/token"
+	"GoLeafServer/leafserver/src/server/msg"
 )
 
 func handleMsg(m interface{}, h interface{}) {
@@ -9,5 +17,20 @@ func handleMsg(m interface{}, h interface{}) {
 }
 
 func init() {
+	skeleton.RegisterChanRPC("Auth", rpcAuth)
+}
+
+// gate 收到 msg.Auth 时调用，返回用户 id 或 error
+func rpcAuth(args []interface{}) interface{} {
+	m := args[0].(*msg.Auth)
+	a := args[1].(gate.Agent)
 
+	userID, err := token.Verify(m.Token, conf.Server.AuthSecret, time.Now())
+	if err != nil {
+		log.Debug("auth %v failed: %v", a.RemoteAddr(), err)
+		a.WriteMsg(&msg.AuthResult{Error: err.Error()})
+		return err
+	}
+	a.WriteMsg(&msg.AuthResult{UserID: userID})
+	return userID
 }
@@ -0,0 +1,52 @@
+package token
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 认证 token 的格式为 <用户 id>.<过期时间 unix 秒>.<签名>，
+// 签名为 HMAC-SHA256(secret, "<用户 id>.<过期时间>") 的 base64url 编码
+
+// 签发 token，用户 id 不能包含 '.'
+func New(userID string, expire time.Time, secret string) (string, error) {
+	if userID == "" || strings.Contains(userID, ".") {
+		return "", errors.New("invalid user id")
+	}
+	payload := userID + "." + strconv.FormatInt(expire.Unix(), 10)
+	return payload + "." + sign(payload, secret), nil
+}
+
+// 检查 token，返回用户 id
+func Verify(token string, secret string, now time.Time) (string, error) {
+	if secret == "" {
+		return "", errors.New("auth disabled")
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] == "" {
+		return "", errors.New("malformed token")
+	}
+	payload := parts[0] + "." + parts[1]
+	if !hmac.Equal([]byte(parts[2]), []byte(sign(payload, secret))) {
+		return "", errors.New("invalid token")
+	}
+	expire, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return "", errors.New("malformed token")
+	}
+	if now.Unix() >= expire {
+		return "", errors.New("token expired")
+	}
+	return parts[0], nil
+}
+
+func sign(payload string, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,40 @@
+package token
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	now := time.Unix(1000, 0)
+	token, err := New("u1", now.Add(time.Hour), "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	userID, err := Verify(token, "secret", now)
+	if err != nil || userID != "u1" {
+		t.Fatalf("got %q, %v", userID, err)
+	}
+
+	for _, c := range []struct {
+		token  string
+		secret string
+		now    time.Time
+		err    string
+	}{
+		{token, "other", now, "invalid token"},
+		{token, "secret", now.Add(time.Hour), "token expired"},
+		{token, "", now, "auth disabled"},
+		{"u2" + strings.TrimPrefix(token, "u1"), "secret", now, "invalid token"},
+		{"u1.x", "secret", now, "malformed token"},
+	} {
+		if _, err := Verify(c.token, c.secret, c.now); err == nil || err.Error() != c.err {
+			t.Errorf("verify %q: got %v, want %v", c.token, err, c.err)
+		}
+	}
+
+	if _, err := New("a.b", now, "secret"); err == nil {
+		t.Error("expected error for user id with '.'")
+	}
+}
@@ -3,8 +3,10 @@ package main
 import (
 	"github.com/name5566/leaf"
 	lconf "github.com/name5566/leaf/conf"
+	"github.com/name5566/leaf/log"
 
 	"GoLeafServer/leafserver/src/server/game"
+	"GoLeafServer/leafserver/src/server/gamedata"
 	"GoLeafServer/leafserver/src/server/gate"
 	"GoLeafServer/leafserver/src/server/login"
 	"GoLeafServer/leafserver/src/server/conf"
@@ -17,6 +19,17 @@ func main() {
 	lconf.ConsolePort = conf.Server.ConsolePort
 	lconf.ProfilePath = conf.Server.ProfilePath
 
+	if conf.Server.GamedataPath != "" {
+		gamedata.Dir = conf.Server.GamedataPath
+	}
+	gamedata.SetBundle(conf.Server.GamedataBundle, conf.Server.GamedataChecksum)
+	if err := gamedata.Load(); err != nil {
+		log.Fatal("gamedata: %v", err)
+	}
+	if v := gamedata.Version(); v != "" {
+		log.Release("gamedata version %v", v)
+	}
+
 	leaf.Run(
 		game.Module,
 		gate.Module,
@@ -0,0 +1,212 @@
+package msg
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+)
+
+// 生成客户端使用的消息类型定义，字段名与 JSON 编码后的名字一致
+
+type clientField struct {
+	name     string
+	typ      reflect.Type
+	optional bool
+}
+
+// 按 encoding/json 的规则列出结构体的字段，匿名结构体字段展开
+func clientFields(t reflect.Type) []clientField {
+	var fields []clientField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, opts = tag[:i], tag[i+1:]
+		}
+		ft := f.Type
+		if ft.Kind() == reflect.Ptr {
+			ft = ft.Elem()
+		}
+		if f.Anonymous && name == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, clientFields(ft)...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, clientField{
+			name:     name,
+			typ:      f.Type,
+			optional: strings.Contains(opts, "omitempty") || f.Type.Kind() == reflect.Ptr,
+		})
+	}
+	return fields
+}
+
+// 已注册的消息以及消息中引用到的结构体，按出现顺序排列
+func clientTypes() ([]reflect.Type, error) {
+	var types []reflect.Type
+	seen := make(map[reflect.Type]bool)
+	var visit func(t reflect.Type) error
+	visit = func(t reflect.Type) error {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			return visit(t.Elem())
+		case reflect.Map:
+			if err := visit(t.Key()); err != nil {
+				return err
+			}
+			return visit(t.Elem())
+		case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+			return fmt.Errorf("unsupported message field type %v", t)
+		case reflect.Struct:
+			if t.Name() == "" {
+				return fmt.Errorf("unsupported anonymous struct %v", t)
+			}
+			if seen[t] {
+				return nil
+			}
+			seen[t] = true
+			types = append(types, t)
+			for _, f := range clientFields(t) {
+				if err := visit(f.typ); err != nil {
+					return fmt.Errorf("%v.%v: %v", t.Name(), f.name, err)
+				}
+			}
+		}
+		return nil
+	}
+	for _, t := range msgTypes {
+		if err := visit(t); err != nil {
+			return nil, err
+		}
+	}
+	return types, nil
+}
+
+func tsType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return tsType(t.Elem())
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		// []byte 按 base64 字符串编码
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "string"
+		}
+		return tsType(t.Elem()) + "[]"
+	case reflect.Map:
+		return "{ [key: string]: " + tsType(t.Elem()) + " }"
+	case reflect.Struct:
+		return t.Name()
+	case reflect.Interface:
+		return "any"
+	}
+	return "number"
+}
+
+func csType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return csType(t.Elem())
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int8:
+		return "sbyte"
+	case reflect.Int16:
+		return "short"
+	case reflect.Int32:
+		return "int"
+	case reflect.Int, reflect.Int64:
+		return "long"
+	case reflect.Uint8:
+		return "byte"
+	case reflect.Uint16:
+		return "ushort"
+	case reflect.Uint32:
+		return "uint"
+	case reflect.Uint, reflect.Uint64, reflect.Uintptr:
+		return "ulong"
+	case reflect.Float32:
+		return "float"
+	case reflect.Float64:
+		return "double"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return "byte[]"
+		}
+		return "List<" + csType(t.Elem()) + ">"
+	case reflect.Map:
+		return "Dictionary<" + csType(t.Key()) + ", " + csType(t.Elem()) + ">"
+	case reflect.Struct:
+		return t.Name()
+	}
+	return "object"
+}
+
+// 输出 TypeScript 定义，Messages 描述了 {"消息名": 消息} 的编码格式
+func WriteTypeScript(w io.Writer) error {
+	types, err := clientTypes()
+	if err != nil {
+		return err
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "// Code generated by msggen. DO NOT EDIT.\n")
+	for _, t := range types {
+		fmt.Fprintf(b, "\nexport interface %v {\n", t.Name())
+		for _, f := range clientFields(t) {
+			optional := ""
+			if f.optional {
+				optional = "?"
+			}
+			fmt.Fprintf(b, "\t%v%v: %v;\n", f.name, optional, tsType(f.typ))
+		}
+		fmt.Fprintf(b, "}\n")
+	}
+	fmt.Fprintf(b, "\nexport interface Messages {\n")
+	for _, t := range msgTypes {
+		fmt.Fprintf(b, "\t%v?: %v;\n", t.Name(), t.Name())
+	}
+	fmt.Fprintf(b, "}\n")
+	return b.Flush()
+}
+
+// 输出 C# 定义
+func WriteCSharp(w io.Writer, namespace string) error {
+	types, err := clientTypes()
+	if err != nil {
+		return err
+	}
+
+	b := bufio.NewWriter(w)
+	fmt.Fprintf(b, "// Code generated by msggen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(b, "using System;\nusing System.Collections.Generic;\n\n")
+	fmt.Fprintf(b, "namespace %v\n{\n", namespace)
+	for i, t := range types {
+		if i > 0 {
+			fmt.Fprintf(b, "\n")
+		}
+		fmt.Fprintf(b, "    [Serializable]\n    public class %v\n    {\n", t.Name())
+		for _, f := range clientFields(t) {
+			fmt.Fprintf(b, "        public %v %v;\n", csType(f.typ), f.name)
+		}
+		fmt.Fprintf(b, "    }\n")
+	}
+	fmt.Fprintf(b, "}\n")
+	return b.Flush()
+}
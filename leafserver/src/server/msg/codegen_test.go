@@ -0,0 +1,60 @@
+package msg
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type TestItem struct {
+	Id    int32
+	Count int `json:"count,omitempty"`
+}
+
+type TestBag struct {
+	Items  []*TestItem
+	Owner  string `json:"owner"`
+	secret int
+}
+
+func TestWriteTypeScript(t *testing.T) {
+	defer func(types []reflect.Type) { msgTypes = types }(msgTypes)
+	msgTypes = append(msgTypes, reflect.TypeOf(TestBag{}))
+
+	buf := new(bytes.Buffer)
+	if err := WriteTypeScript(buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"export interface Hello {\n\tName: string;\n}",
+		"export interface TestBag {\n\tItems: TestItem[];\n\towner: string;\n}",
+		"export interface TestItem {\n\tId: number;\n\tcount?: number;\n}",
+		"\tTestBag?: TestBag;",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("missing %q in:\n%v", s, buf)
+		}
+	}
+}
+
+func TestWriteCSharp(t *testing.T) {
+	defer func(types []reflect.Type) { msgTypes = types }(msgTypes)
+	msgTypes = append(msgTypes, reflect.TypeOf(TestBag{}))
+
+	buf := new(bytes.Buffer)
+	if err := WriteCSharp(buf, "Msg"); err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		"namespace Msg",
+		"public class TestBag",
+		"public List<TestItem> Items;",
+		"public int Id;",
+		"public long count;",
+	} {
+		if !strings.Contains(buf.String(), s) {
+			t.Errorf("missing %q in:\n%v", s, buf)
+		}
+	}
+}
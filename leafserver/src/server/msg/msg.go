@@ -2,13 +2,41 @@ package msg
 
 import (
 	"github.com/name5566/leaf/network/json"
+	"reflect"
 )
 
 var Processor = json.NewProcessor()
 
+// 已注册的消息类型，按注册顺序排列，用于生成客户端代码
+var msgTypes []reflect.Type
+
 func init() {
 	// 这里我们注册了一个 JSON 消息 Hello
-	Processor.Register(&Hello{})
+	register(&Hello{})
+	register(&Auth{})
+	register(&AuthResult{})
+	register(&Ping{})
+	register(&Pong{})
+	register(&Kick{})
+	register(&ResumeInfo{})
+	register(&Resume{})
+	register(&ResumeResult{})
+	register(&Ack{})
+}
+
+// 注册消息并记录消息类型
+func register(m interface{}) {
+	Processor.Register(m)
+	msgTypes = append(msgTypes, reflect.TypeOf(m).Elem())
+}
+
+// 已注册的消息名，按注册顺序排列
+func Names() []string {
+	names := make([]string, len(msgTypes))
+	for i, t := range msgTypes {
+		names[i] = t.Name()
+	}
+	return names
 }
 
 // 一个结构体定义了一个 JSON 消息的格式
@@ -16,3 +44,48 @@ func init() {
 type Hello struct {
 	Name string
 }
+
+// 连接建立后客户端发送的第一个消息，Token 由登录服务签发
+type Auth struct {
+	Token string
+}
+
+// Auth 的结果，Error 为空表示成功，失败后连接会被关闭
+type AuthResult struct {
+	UserID string
+	Error  string `json:",omitempty"`
+}
+
+// 心跳，由 gate 处理，收到 Ping 时回复 Pong，任何消息都会刷新连接的空闲时间；
+// 服务端发出的 Ping 和 Pong 不计入 Ack 和 Resume 的消息数
+type Ping struct {
+}
+
+type Pong struct {
+}
+
+// 被踢下线的原因，之后连接会被关闭
+type Kick struct {
+	Reason string
+}
+
+// 认证成功后发送的会话恢复凭证，之后收到的消息从 1 开始计数
+type ResumeInfo struct {
+	Token string
+}
+
+// 断线后在新连接上发送，代替 Auth，Seq 为已经收到的消息数
+type Resume struct {
+	Token string
+	Seq   uint64
+}
+
+// Resume 的结果，成功后按顺序重发 Seq 之后的消息，失败时需要重新登录
+type ResumeResult struct {
+	Error string `json:",omitempty"`
+}
+
+// 客户端定期确认已经收到的消息数，服务端不再保留这些消息
+type Ack struct {
+	Seq uint64
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"GoLeafServer/leafserver/src/server/msg"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+// 根据 msg 包中注册的消息生成客户端类型定义
+var (
+	tsFile      = flag.String("ts", "", "TypeScript output file")
+	csFile      = flag.String("cs", "", "C# output file")
+	csNamespace = flag.String("ns", "Msg", "C# namespace")
+)
+
+func main() {
+	flag.Parse()
+	if *tsFile == "" && *csFile == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *tsFile != "" {
+		if err := writeFile(*tsFile, msg.WriteTypeScript); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	if *csFile != "" {
+		err := writeFile(*csFile, func(w io.Writer) error {
+			return msg.WriteCSharp(w, *csNamespace)
+		})
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+}
+
+func writeFile(name string, write func(w io.Writer) error) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return fmt.Errorf("%v: %v", name, err)
+	}
+	return f.Close()
+}
@@ -0,0 +1,275 @@
+package Rf
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+//命名索引，可以由多个字段组成复合主键，也可以不唯一
+//范围索引只能建立在一个数值字段上，记录按该字段排序
+
+type indexDef struct {
+	name   string
+	fields []int
+	unique bool
+	sorted bool
+}
+
+type namedIndex struct {
+	def     *indexDef
+	records map[interface{}][]interface{}
+	keys    []reflect.Value //范围索引排序后的键
+	sorted  []interface{}   //范围索引排序后的记录
+}
+
+//添加唯一索引，多个字段时为复合主键，键重复时Read返回错误
+func (rf *RecordFile) AddIndex(name string, fields ...string) error {
+	return rf.addIndex(&indexDef{name: name, unique: true}, fields)
+}
+
+//添加不唯一索引，LookupAll返回全部匹配的记录
+func (rf *RecordFile) AddMultiIndex(name string, fields ...string) error {
+	return rf.addIndex(&indexDef{name: name}, fields)
+}
+
+//添加范围索引，字段必须为数值类型，Range按区间查询
+func (rf *RecordFile) AddRangeIndex(name string, field string) error {
+	return rf.addIndex(&indexDef{name: name, sorted: true}, []string{field})
+}
+
+func (rf *RecordFile) addIndex(def *indexDef, fields []string) error {
+	if def.name == "" {
+		return fmt.Errorf("index name required")
+	}
+	if rf.indexDef(def.name) != nil {
+		return fmt.Errorf("index %v already exists", def.name)
+	}
+	if len(fields) == 0 {
+		return fmt.Errorf("index %v: no field", def.name)
+	}
+	for _, name := range fields {
+		f, ok := rf.typeRecord.FieldByName(name)
+		if !ok || len(f.Index) != 1 || f.PkgPath != "" {
+			return fmt.Errorf("index %v: no exported field %v", def.name, name)
+		}
+		switch f.Type.Kind() {
+		case reflect.Struct, reflect.Slice, reflect.Map, reflect.Array:
+			return fmt.Errorf("index %v: could not index %s field %v", def.name, f.Type.Kind(), name)
+		}
+		if def.sorted && numberClass(f.Type.Kind()) == 0 {
+			return fmt.Errorf("index %v: range index requires a numeric field, got %v %v", def.name, name, f.Type)
+		}
+		def.fields = append(def.fields, f.Index[0])
+	}
+
+	rf.defs = append(rf.defs, def)
+	//已经读取过数据时立即建立索引
+	if rf.records != nil {
+		index, err := rf.makeNamedIndex(def, rf.records)
+		if err != nil {
+			rf.defs = rf.defs[:len(rf.defs)-1]
+			return err
+		}
+		rf.named[def.name] = index
+	}
+	return nil
+}
+
+func (rf *RecordFile) indexDef(name string) *indexDef {
+	for _, def := range rf.defs {
+		if def.name == name {
+			return def
+		}
+	}
+	return nil
+}
+
+//按全部命名索引建立索引
+func (rf *RecordFile) makeNamedIndexes(records []interface{}) (map[string]*namedIndex, error) {
+	named := make(map[string]*namedIndex, len(rf.defs))
+	for _, def := range rf.defs {
+		index, err := rf.makeNamedIndex(def, records)
+		if err != nil {
+			return nil, err
+		}
+		named[def.name] = index
+	}
+	return named, nil
+}
+
+func (rf *RecordFile) makeNamedIndex(def *indexDef, records []interface{}) (*namedIndex, error) {
+	index := &namedIndex{def: def, records: make(map[interface{}][]interface{})}
+	values := make([]reflect.Value, len(def.fields))
+	for n, r := range records {
+		record := reflect.ValueOf(r).Elem()
+		for i, f := range def.fields {
+			values[i] = record.Field(f)
+		}
+		key := makeKey(values)
+		if def.unique && len(index.records[key]) > 0 {
+			return nil, fmt.Errorf("index %v error: duplicate key %v at (row=%v)",
+				def.name, formatKey(values), n+1)
+		}
+		index.records[key] = append(index.records[key], r)
+	}
+
+	if def.sorted {
+		index.sorted = append([]interface{}{}, records...)
+		f := def.fields[0]
+		sort.SliceStable(index.sorted, func(i, j int) bool {
+			return numberLess(reflect.ValueOf(index.sorted[i]).Elem().Field(f),
+				reflect.ValueOf(index.sorted[j]).Elem().Field(f))
+		})
+		index.keys = make([]reflect.Value, len(index.sorted))
+		for i, r := range index.sorted {
+			index.keys[i] = reflect.ValueOf(r).Elem().Field(f)
+		}
+	}
+	return index, nil
+}
+
+//单字段时键为字段值，多字段时为各字段值拼接的字符串
+func makeKey(values []reflect.Value) interface{} {
+	if len(values) == 1 {
+		return values[0].Interface()
+	}
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprintf("%#v", v.Interface())
+	}
+	return strings.Join(parts, "\x00")
+}
+
+func formatKey(values []reflect.Value) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v.Interface())
+	}
+	return "(" + strings.Join(parts, ", ") + ")"
+}
+
+//查询参数转换为字段类型，例如int常量查询int32字段
+func (rf *RecordFile) lookupKey(def *indexDef, keys []interface{}) (interface{}, error) {
+	if len(keys) != len(def.fields) {
+		return nil, fmt.Errorf("index %v: %v keys required, got %v", def.name, len(def.fields), len(keys))
+	}
+	values := make([]reflect.Value, len(keys))
+	for i, k := range keys {
+		v, err := convertKey(k, rf.typeRecord.Field(def.fields[i]).Type)
+		if err != nil {
+			return nil, fmt.Errorf("index %v: %v", def.name, err)
+		}
+		values[i] = v
+	}
+	return makeKey(values), nil
+}
+
+func convertKey(k interface{}, t reflect.Type) (reflect.Value, error) {
+	v := reflect.ValueOf(k)
+	if !v.IsValid() {
+		return v, fmt.Errorf("nil key")
+	}
+	if v.Type() == t {
+		return v, nil
+	}
+	if numberClass(v.Kind()) != 0 && numberClass(t.Kind()) != 0 {
+		//溢出、符号改变或者丢失小数部分时不能查询，例如-1转换为uint64后可以转换回-1
+		c := v.Convert(t)
+		if c.Convert(v.Type()).Interface() != k || negative(v) != negative(c) {
+			return v, fmt.Errorf("key %v overflows %v", k, t)
+		}
+		return c, nil
+	}
+	if v.Kind() == t.Kind() && v.Type().ConvertibleTo(t) {
+		return v.Convert(t), nil
+	}
+	return v, fmt.Errorf("key %v (%T) is not %v", k, k, t)
+}
+
+func (rf *RecordFile) namedIndex(name string) *namedIndex {
+	index := rf.named[name]
+	if index == nil {
+		panic(fmt.Sprintf("index %v not found", name))
+	}
+	return index
+}
+
+//按唯一索引查找，不存在时返回nil，keys按AddIndex的字段顺序
+func (rf *RecordFile) Lookup(name string, keys ...interface{}) interface{} {
+	records := rf.LookupAll(name, keys...)
+	if len(records) == 0 {
+		return nil
+	}
+	return records[0]
+}
+
+//按索引查找全部匹配的记录，按读取顺序返回
+func (rf *RecordFile) LookupAll(name string, keys ...interface{}) []interface{} {
+	index := rf.namedIndex(name)
+	key, err := rf.lookupKey(index.def, keys)
+	if err != nil {
+		return nil
+	}
+	records := index.records[key]
+	return records[:len(records):len(records)]
+}
+
+//按范围索引查找min<=字段值<=max的记录，按字段值排序返回
+func (rf *RecordFile) Range(name string, min interface{}, max interface{}) []interface{} {
+	index := rf.namedIndex(name)
+	if !index.def.sorted {
+		panic(fmt.Sprintf("index %v is not a range index", name))
+	}
+	t := rf.typeRecord.Field(index.def.fields[0]).Type
+	lo, err := convertKey(min, t)
+	if err != nil {
+		return nil
+	}
+	hi, err := convertKey(max, t)
+	if err != nil {
+		return nil
+	}
+	i := sort.Search(len(index.keys), func(i int) bool { return !numberLess(index.keys[i], lo) })
+	j := sort.Search(len(index.keys), func(i int) bool { return numberLess(hi, index.keys[i]) })
+	if i >= j {
+		return nil
+	}
+	//限制容量，调用者append时不会改写索引
+	return index.sorted[i:j:j]
+}
+
+func numberClass(kind reflect.Kind) int {
+	switch kind {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return 1
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return 2
+	case reflect.Float32, reflect.Float64:
+		return 3
+	}
+	return 0
+}
+
+func negative(v reflect.Value) bool {
+	switch numberClass(v.Kind()) {
+	case 1:
+		return v.Int() < 0
+	case 3:
+		return v.Float() < 0
+	}
+	return false
+}
+
+//a、b为同一数值类型
+func numberLess(a reflect.Value, b reflect.Value) bool {
+	switch numberClass(a.Kind()) {
+	case 1:
+		return a.Int() < b.Int()
+	case 2:
+		return a.Uint() < b.Uint()
+	default:
+		return a.Float() < b.Float()
+	}
+}
@@ -0,0 +1,153 @@
+package Rf
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+type reward struct {
+	Id      int32
+	Chapter int
+	Level   int
+	Class   string
+	Weight  float64
+}
+
+func readRewards(t *testing.T, rf *RecordFile) {
+	f, err := ioutil.TempFile("", "reward")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("Id,Chapter,Level,Class,Weight\n" +
+		"1,3,10,warrior,0.5\n" +
+		"2,3,10,mage,1.5\n" +
+		"3,1,20,warrior,2\n" +
+		"4,3,20,mage,1\n")
+	f.Close()
+	if err := rf.Read(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func ids(records []interface{}) []int32 {
+	ids := make([]int32, len(records))
+	for i, r := range records {
+		ids[i] = r.(*reward).Id
+	}
+	return ids
+}
+
+func TestNamedIndex(t *testing.T) {
+	rf, err := New(reward{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.AddIndex("Id", "Id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.AddIndex("LevelClass", "Level", "Class"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.AddMultiIndex("Chapter", "Chapter"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.AddRangeIndex("Weight", "Weight"); err != nil {
+		t.Fatal(err)
+	}
+	readRewards(t, rf)
+
+	if r, ok := rf.Lookup("Id", 2).(*reward); !ok || r.Class != "mage" {
+		t.Errorf("Lookup Id 2: %v", rf.Lookup("Id", 2))
+	}
+	if r := rf.Lookup("Id", 2.5); r != nil {
+		t.Errorf("Lookup Id 2.5: %v", r)
+	}
+	if r, ok := rf.Lookup("LevelClass", 20, "mage").(*reward); !ok || r.Id != 4 {
+		t.Errorf("Lookup LevelClass: %v", rf.Lookup("LevelClass", 20, "mage"))
+	}
+	if r := rf.Lookup("LevelClass", 20); r != nil {
+		t.Errorf("Lookup with missing key: %v", r)
+	}
+	if got := ids(rf.LookupAll("Chapter", 3)); !reflect.DeepEqual(got, []int32{1, 2, 4}) {
+		t.Errorf("LookupAll Chapter 3: %v", got)
+	}
+	if got := ids(rf.Range("Weight", 1, 2)); !reflect.DeepEqual(got, []int32{4, 2, 3}) {
+		t.Errorf("Range Weight: %v", got)
+	}
+	if got := rf.Range("Weight", 3, 4); len(got) != 0 {
+		t.Errorf("empty Range: %v", got)
+	}
+	//修改返回的切片不影响索引
+	_ = append(rf.Range("Weight", 0, 1), &reward{Id: 5})
+	first := append(rf.LookupAll("Chapter", 3), &reward{Id: 5})
+	_ = append(rf.LookupAll("Chapter", 3), &reward{Id: 6})
+	if got := ids(first); !reflect.DeepEqual(got, []int32{1, 2, 4, 5}) {
+		t.Errorf("append to LookupAll: %v", got)
+	}
+	if got := ids(rf.Range("Weight", 0, 2)); !reflect.DeepEqual(got, []int32{1, 4, 2, 3}) {
+		t.Errorf("Range after append: %v", got)
+	}
+	if got := ids(rf.LookupAll("Chapter", 3)); !reflect.DeepEqual(got, []int32{1, 2, 4}) {
+		t.Errorf("LookupAll after append: %v", got)
+	}
+
+	if r := rf.Lookup("Id", uint64(1<<64-1)); r != nil {
+		t.Errorf("Lookup Id MaxUint64: %v", r)
+	}
+
+	if err := rf.AddIndex("Chapter2", "Chapter"); err == nil {
+		t.Error("expected duplicate key error")
+	}
+	if err := rf.AddRangeIndex("Class", "Class"); err == nil {
+		t.Error("expected non-numeric range index error")
+	}
+	if err := rf.AddIndex("Id", "Level"); err == nil {
+		t.Error("expected duplicate index name error")
+	}
+	if err := rf.AddMultiIndex("Class", "Class"); err != nil {
+		t.Fatal(err)
+	}
+	if got := ids(rf.LookupAll("Class", "warrior")); !reflect.DeepEqual(got, []int32{1, 3}) {
+		t.Errorf("LookupAll Class after Read: %v", got)
+	}
+}
+
+func TestUnsignedKey(t *testing.T) {
+	type item struct {
+		Id uint64
+	}
+	rf, err := New(item{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.AddIndex("Id", "Id"); err != nil {
+		t.Fatal(err)
+	}
+	if err := rf.AddRangeIndex("Range", "Id"); err != nil {
+		t.Fatal(err)
+	}
+	f, err := ioutil.TempFile("", "item")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("Id\n1\n18446744073709551615\n")
+	f.Close()
+	if err := rf.Read(f.Name()); err != nil {
+		t.Fatal(err)
+	}
+
+	if r := rf.Lookup("Id", 1); r == nil {
+		t.Error("Lookup Id 1: nil")
+	}
+	//-1不能查询uint64字段
+	if r := rf.Lookup("Id", -1); r != nil {
+		t.Errorf("Lookup Id -1: %v", r)
+	}
+	if got := rf.Range("Range", -1, 1); got != nil {
+		t.Errorf("Range -1: %v", got)
+	}
+}
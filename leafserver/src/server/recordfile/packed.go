@@ -0,0 +1,465 @@
+package Rf
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"reflect"
+	"unicode"
+	"unicode/utf8"
+)
+
+//二进制配置格式
+//
+//	"LFRB" 版本(1字节) 标志(1字节) 内容
+//	内容: 列数 {列名 类型 数组元素类型} 行数 {每列的值}
+//
+//整数为zigzag varint，浮点数为小端float64，字符串和json为长度(uvarint)加内容，
+//数组为元素个数(uvarint)加元素，标志PackedGzip表示内容经过gzip压缩
+
+var PackedMagic = "LFRB"
+
+const PackedVersion = 1
+
+const PackedGzip = 1
+
+//列类型
+const (
+	PackedInt = 1 + iota
+	PackedFloat
+	PackedString
+	PackedBool
+	PackedArray
+	PackedJSON
+)
+
+type PackedColumn struct {
+	Name string
+	Kind uint8
+	Elem uint8 //数组元素类型
+}
+
+//写入二进制配置，rows中的值为int64、float64、string、bool、[]interface{}，
+//PackedJSON列为json.RawMessage或任意可以json编码的值
+func WritePacked(w io.Writer, columns []PackedColumn, rows [][]interface{}, compress bool) error {
+	header := []byte(PackedMagic)
+	flags := byte(0)
+	if compress {
+		flags |= PackedGzip
+	}
+	header = append(header, PackedVersion, flags)
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+
+	var gw *gzip.Writer
+	if compress {
+		gw = gzip.NewWriter(w)
+		w = gw
+	}
+	bw := bufio.NewWriter(w)
+	pw := &packedWriter{w: bw}
+	pw.uvarint(uint64(len(columns)))
+	for _, col := range columns {
+		pw.str(col.Name)
+		pw.byte(col.Kind)
+		pw.byte(col.Elem)
+	}
+	pw.uvarint(uint64(len(rows)))
+	for n, row := range rows {
+		if len(row) != len(columns) {
+			return fmt.Errorf("row %v, field count mismatch: %v (row) %v (columns)",
+				n, len(row), len(columns))
+		}
+		for i, col := range columns {
+			if err := pw.value(col.Kind, col.Elem, row[i]); err != nil {
+				return fmt.Errorf("write field (row=%v, col=%v) error: %v", n, i, err)
+			}
+		}
+	}
+	if pw.err != nil {
+		return pw.err
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	if gw != nil {
+		return gw.Close()
+	}
+	return nil
+}
+
+type packedWriter struct {
+	w   *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+	err error
+}
+
+func (pw *packedWriter) write(b []byte) {
+	if pw.err == nil {
+		_, pw.err = pw.w.Write(b)
+	}
+}
+
+func (pw *packedWriter) byte(b byte) {
+	pw.write([]byte{b})
+}
+
+func (pw *packedWriter) uvarint(v uint64) {
+	pw.write(pw.buf[:binary.PutUvarint(pw.buf[:], v)])
+}
+
+func (pw *packedWriter) str(s string) {
+	pw.uvarint(uint64(len(s)))
+	pw.write([]byte(s))
+}
+
+func (pw *packedWriter) value(kind uint8, elem uint8, v interface{}) error {
+	switch kind {
+	case PackedInt:
+		i, ok := v.(int64)
+		if !ok {
+			return fmt.Errorf("int64 required, got %T", v)
+		}
+		pw.write(pw.buf[:binary.PutVarint(pw.buf[:], i)])
+	case PackedFloat:
+		f, ok := v.(float64)
+		if !ok {
+			return fmt.Errorf("float64 required, got %T", v)
+		}
+		binary.LittleEndian.PutUint64(pw.buf[:8], math.Float64bits(f))
+		pw.write(pw.buf[:8])
+	case PackedString:
+		s, ok := v.(string)
+		if !ok {
+			return fmt.Errorf("string required, got %T", v)
+		}
+		pw.str(s)
+	case PackedBool:
+		b, ok := v.(bool)
+		if !ok {
+			return fmt.Errorf("bool required, got %T", v)
+		}
+		if b {
+			pw.byte(1)
+		} else {
+			pw.byte(0)
+		}
+	case PackedArray:
+		arr, ok := v.([]interface{})
+		if !ok {
+			return fmt.Errorf("[]interface{} required, got %T", v)
+		}
+		pw.uvarint(uint64(len(arr)))
+		for _, e := range arr {
+			if err := pw.value(elem, 0, e); err != nil {
+				return err
+			}
+		}
+	case PackedJSON:
+		data, ok := v.(json.RawMessage)
+		if !ok {
+			var err error
+			if data, err = json.Marshal(v); err != nil {
+				return err
+			}
+		}
+		pw.str(string(data))
+	default:
+		return fmt.Errorf("invalid packed kind %v", kind)
+	}
+	return nil
+}
+
+//读取WritePacked写入的二进制配置，列按名字对应st的字段（标签中的name或首字母大写的字段名），
+//字段的赋值方式在读取前确定，不需要逐个单元格解析字符串
+func (rf *RecordFile) ReadPacked(name string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return rf.readPacked(file)
+}
+
+func (rf *RecordFile) readPacked(r io.Reader) error {
+	header := make([]byte, len(PackedMagic)+2)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return err
+	}
+	if string(header[:len(PackedMagic)]) != PackedMagic {
+		return errors.New("invalid packed file")
+	}
+	if header[len(PackedMagic)] != PackedVersion {
+		return fmt.Errorf("unsupported packed version %v", header[len(PackedMagic)])
+	}
+	if header[len(PackedMagic)+1]&PackedGzip != 0 {
+		gr, err := gzip.NewReader(r)
+		if err != nil {
+			return err
+		}
+		defer gr.Close()
+		r = gr
+	}
+	pr := &packedReader{r: bufio.NewReader(r)}
+
+	typeRecord := rf.typeRecord
+	//长度和数量都来自文件，只按实际读到的数据分配内存，数据不足时返回错误
+	numColumn := pr.uvarint()
+	var columns []PackedColumn
+	var fields []int
+	covered := make([]bool, typeRecord.NumField())
+	for i := uint64(0); i < numColumn && pr.err == nil; i++ {
+		col := PackedColumn{Name: pr.str(), Kind: pr.byte(), Elem: pr.byte()}
+		if pr.err != nil {
+			break
+		}
+		field := rf.fieldByColumn(col.Name)
+		columns = append(columns, col)
+		fields = append(fields, field)
+		if field < 0 {
+			continue
+		}
+		covered[field] = true
+		f := typeRecord.Field(field)
+		if !packedAssignable(col.Kind, col.Elem, f.Type) {
+			return fmt.Errorf("column %v: cannot read %v into %v field %v",
+				col.Name, col.Kind, f.Type, f.Name)
+		}
+	}
+
+	numRecord := pr.uvarint()
+	if pr.err != nil {
+		return pr.err
+	}
+	if len(columns) == 0 && numRecord > 0 {
+		return errors.New("invalid packed file: records without columns")
+	}
+	//与readLines相同，没有对应列时使用default，没有default时报错
+	for i, tag := range rf.tags {
+		if !covered[i] && !tag.hasDefault {
+			return fmt.Errorf("column %v not found", tag.name)
+		}
+	}
+
+	var records []interface{}
+	for n := uint64(0); n < numRecord; n++ {
+		value := reflect.New(typeRecord)
+		record := value.Elem()
+		for i, col := range columns {
+			var field reflect.Value
+			if fields[i] >= 0 {
+				field = record.Field(fields[i])
+			}
+			if err := pr.value(col.Kind, col.Elem, field); err != nil {
+				return fmt.Errorf("parse field (row=%v, col=%v) error: %v",
+					n+1, i, err)
+			}
+		}
+		if err := rf.packedDefaults(record, covered); err != nil {
+			return fmt.Errorf("parse field (row=%v) error: %v", n+1, err)
+		}
+		records = append(records, value.Interface())
+	}
+
+	return rf.setRecords(records)
+}
+
+//与readLines相同，没有对应列或者字符串为空时使用default，required的字段不能为空；
+//其他类型的空单元格写入时已经是零值，无法区分
+func (rf *RecordFile) packedDefaults(record reflect.Value, covered []bool) error {
+	for i, tag := range rf.tags {
+		field := record.Field(i)
+		if covered[i] && (field.Kind() != reflect.String || field.Len() > 0) {
+			continue
+		}
+		if tag.required {
+			return fmt.Errorf("%v is required", tag.name)
+		}
+		if tag.hasDefault {
+			if err := setField(field, tag.def); err != nil {
+				return fmt.Errorf("%v: %v", tag.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+//列对应的字段，先按标签中的name查找，再按首字母大写的字段名查找，没有时返回-1
+func (rf *RecordFile) fieldByColumn(name string) int {
+	for i, tag := range rf.tags {
+		if tag.named && tag.name == name {
+			return i
+		}
+	}
+	f, ok := rf.typeRecord.FieldByName(exportedName(name))
+	if !ok || len(f.Index) != 1 {
+		return -1
+	}
+	return f.Index[0]
+}
+
+func exportedName(name string) string {
+	r, size := utf8.DecodeRuneInString(name)
+	return string(unicode.ToUpper(r)) + name[size:]
+}
+
+func packedAssignable(kind uint8, elem uint8, t reflect.Type) bool {
+	switch kind {
+	case PackedInt:
+		switch t.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return true
+		}
+	case PackedFloat:
+		return t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64
+	case PackedString:
+		return t.Kind() == reflect.String
+	case PackedBool:
+		return t.Kind() == reflect.Bool
+	case PackedArray:
+		return (t.Kind() == reflect.Slice || t.Kind() == reflect.Array) &&
+			packedAssignable(elem, 0, t.Elem())
+	case PackedJSON:
+		return true
+	}
+	return false
+}
+
+type packedReader struct {
+	r   *bufio.Reader
+	buf [8]byte
+	err error
+}
+
+func (pr *packedReader) byte() byte {
+	if pr.err != nil {
+		return 0
+	}
+	var b byte
+	b, pr.err = pr.r.ReadByte()
+	return b
+}
+
+func (pr *packedReader) uvarint() uint64 {
+	if pr.err != nil {
+		return 0
+	}
+	var v uint64
+	v, pr.err = binary.ReadUvarint(pr.r)
+	return v
+}
+
+func (pr *packedReader) varint() int64 {
+	if pr.err != nil {
+		return 0
+	}
+	var v int64
+	v, pr.err = binary.ReadVarint(pr.r)
+	return v
+}
+
+//按实际读到的数据增长，长度超过剩余数据时返回错误
+func (pr *packedReader) bytes() []byte {
+	n := pr.uvarint()
+	if pr.err != nil {
+		return nil
+	}
+	if n > math.MaxInt64 {
+		pr.err = fmt.Errorf("invalid length %v", n)
+		return nil
+	}
+	var buf bytes.Buffer
+	if _, err := io.CopyN(&buf, pr.r, int64(n)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		pr.err = err
+	}
+	return buf.Bytes()
+}
+
+func (pr *packedReader) str() string {
+	return string(pr.bytes())
+}
+
+//读取一个值，field无效时丢弃
+func (pr *packedReader) value(kind uint8, elem uint8, field reflect.Value) error {
+	switch kind {
+	case PackedInt:
+		v := pr.varint()
+		if pr.err != nil || !field.IsValid() {
+			break
+		}
+		switch field.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if field.OverflowInt(v) {
+				return fmt.Errorf("value %v overflows %v", v, field.Type())
+			}
+			field.SetInt(v)
+		default:
+			if v < 0 || field.OverflowUint(uint64(v)) {
+				return fmt.Errorf("value %v overflows %v", v, field.Type())
+			}
+			field.SetUint(uint64(v))
+		}
+	case PackedFloat:
+		if pr.err == nil {
+			_, pr.err = io.ReadFull(pr.r, pr.buf[:])
+		}
+		if pr.err == nil && field.IsValid() {
+			field.SetFloat(math.Float64frombits(binary.LittleEndian.Uint64(pr.buf[:])))
+		}
+	case PackedString:
+		s := pr.str()
+		if pr.err == nil && field.IsValid() {
+			field.SetString(s)
+		}
+	case PackedBool:
+		b := pr.byte()
+		if pr.err == nil && field.IsValid() {
+			field.SetBool(b != 0)
+		}
+	case PackedArray:
+		//元素个数来自文件，slice逐个追加，数据不足时在读取元素时出错
+		n := pr.uvarint()
+		if pr.err != nil {
+			break
+		}
+		isSlice := field.IsValid() && field.Kind() == reflect.Slice
+		if isSlice {
+			field.Set(reflect.MakeSlice(field.Type(), 0, 0))
+		}
+		for i := uint64(0); i < n; i++ {
+			var e reflect.Value
+			if isSlice {
+				e = reflect.New(field.Type().Elem()).Elem()
+			} else if field.IsValid() && i < uint64(field.Len()) {
+				e = field.Index(int(i))
+			}
+			if err := pr.value(elem, 0, e); err != nil {
+				return err
+			}
+			if isSlice {
+				field.Set(reflect.Append(field, e))
+			}
+		}
+	case PackedJSON:
+		data := pr.bytes()
+		if pr.err == nil && field.IsValid() {
+			return json.Unmarshal(data, field.Addr().Interface())
+		}
+	default:
+		return fmt.Errorf("invalid packed kind %v", kind)
+	}
+	return pr.err
+}
@@ -0,0 +1,136 @@
+package Rf
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type packedItem struct {
+	Id    int32
+	Name  string
+	Rate  float32
+	Tags  []uint8
+	Attr  map[string]int
+	Valid bool
+}
+
+func TestPacked(t *testing.T) {
+	columns := []PackedColumn{
+		{Name: "id", Kind: PackedInt},
+		{Name: "Name", Kind: PackedString},
+		{Name: "Rate", Kind: PackedFloat},
+		{Name: "Tags", Kind: PackedArray, Elem: PackedInt},
+		{Name: "Attr", Kind: PackedJSON},
+		{Name: "Unused", Kind: PackedString},
+		{Name: "Valid", Kind: PackedBool},
+	}
+	rows := [][]interface{}{
+		{int64(1), "sword", 0.5, []interface{}{int64(1), int64(2)}, json.RawMessage(`{"atk":3}`), "x", true},
+		{int64(2), "shield", 1.0, []interface{}{}, json.RawMessage(`{}`), "", false},
+	}
+
+	for _, compress := range []bool{false, true} {
+		buf := new(bytes.Buffer)
+		if err := WritePacked(buf, columns, rows, compress); err != nil {
+			t.Fatal(err)
+		}
+		rf, err := New(packedItem{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rf.readPacked(buf); err != nil {
+			t.Fatal(err)
+		}
+		if rf.NumRecord() != 2 {
+			t.Fatalf("got %v records", rf.NumRecord())
+		}
+		want := &packedItem{Id: 1, Name: "sword", Rate: 0.5, Tags: []uint8{1, 2}, Attr: map[string]int{"atk": 3}, Valid: true}
+		if got := rf.Record(0); !reflect.DeepEqual(got, want) {
+			t.Errorf("got %+v, want %+v", got, want)
+		}
+	}
+}
+
+func TestPackedOverflow(t *testing.T) {
+	buf := new(bytes.Buffer)
+	columns := []PackedColumn{{Name: "Id", Kind: PackedInt}}
+	if err := WritePacked(buf, columns, [][]interface{}{{int64(1 << 40)}}, false); err != nil {
+		t.Fatal(err)
+	}
+	rf, _ := New(struct{ Id int8 }{})
+	if err := rf.readPacked(buf); err == nil || !strings.Contains(err.Error(), "overflows") {
+		t.Errorf("expected overflow error, got %v", err)
+	}
+}
+
+func TestPackedCorrupt(t *testing.T) {
+	buf := new(bytes.Buffer)
+	columns := []PackedColumn{{Name: "Name", Kind: PackedString}, {Name: "Tags", Kind: PackedArray, Elem: PackedInt}}
+	if err := WritePacked(buf, columns, [][]interface{}{{"sword", []interface{}{int64(1)}}}, false); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	header := []byte(PackedMagic + "\x01\x00")
+	huge := []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0x01}
+	cases := map[string][]byte{
+		"truncated": data[:len(data)-1],
+		"columns":   append(append([]byte{}, header...), huge...),
+		"records":   append(append(append([]byte{}, header...), 0), huge...),
+		"string":    append(append(append([]byte{}, header...), 1), huge...),
+		"array": append(append(append([]byte{}, header...),
+			1, 4, 'T', 'a', 'g', 's', PackedArray, PackedInt, 1), huge...),
+	}
+	type item struct {
+		Name string `rf:"default=x"`
+		Tags []int  `rf:"default=[]"`
+	}
+	for name, data := range cases {
+		rf, _ := New(item{})
+		if err := rf.readPacked(bytes.NewReader(data)); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+}
+
+func TestPackedDefaults(t *testing.T) {
+	type item struct {
+		Id    int
+		Name  string `rf:"default=none"`
+		Level int    `rf:"default=1"`
+	}
+	buf := new(bytes.Buffer)
+	columns := []PackedColumn{{Name: "Id", Kind: PackedInt}, {Name: "Name", Kind: PackedString}}
+	rows := [][]interface{}{{int64(1), ""}, {int64(2), "sword"}}
+	if err := WritePacked(buf, columns, rows, false); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	rf, _ := New(item{})
+	if err := rf.readPacked(bytes.NewReader(data)); err != nil {
+		t.Fatal(err)
+	}
+	if got := rf.Record(0).(*item); *got != (item{Id: 1, Name: "none", Level: 1}) {
+		t.Errorf("got %+v", got)
+	}
+	if got := rf.Record(1).(*item); got.Name != "sword" {
+		t.Errorf("got %+v", got)
+	}
+
+	rf, _ = New(struct {
+		Id   int
+		Name string `rf:"required"`
+	}{})
+	if err := rf.readPacked(bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), "required") {
+		t.Errorf("expected required error, got %v", err)
+	}
+	rf, _ = New(struct {
+		Id    int
+		Level int
+	}{})
+	if err := rf.readPacked(bytes.NewReader(data)); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("expected missing column error, got %v", err)
+	}
+}
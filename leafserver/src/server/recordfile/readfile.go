@@ -2,12 +2,11 @@ package Rf
 
 import (
 	"encoding/csv"
-	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"reflect"
-	"strconv"
 )
 
 //leaf框架配置读取修改
@@ -21,9 +20,13 @@ type Index map[interface{}]interface{}
 type RecordFile struct {
 	Comma      rune
 	Comment    rune
+	Sheet      string //xlsx的sheet，为空时读取第一个
 	typeRecord reflect.Type
 	records    []interface{}
 	indexes    []Index
+	tags       []*fieldTag
+	defs       []*indexDef
+	named      map[string]*namedIndex
 }
 
 func New(st interface{}) (*RecordFile, error) {
@@ -31,8 +34,13 @@ func New(st interface{}) (*RecordFile, error) {
 	if typeRecord == nil || typeRecord.Kind() != reflect.Struct {
 		return nil, errors.New("st must be a struct")
 	}
+	var tags []*fieldTag
 	for i := 0; i < typeRecord.NumField(); i++ {
 		f := typeRecord.Field(i)
+		//未导出的字段无法赋值和读取
+		if f.PkgPath != "" {
+			return nil, fmt.Errorf("unexported field %v", f.Name)
+		}
 
 		kind := f.Type.Kind()
 		switch kind {
@@ -58,8 +66,12 @@ func New(st interface{}) (*RecordFile, error) {
 			return nil, fmt.Errorf("invalid type: %v %s",
 				f.Name, kind)
 		}
-		tag := f.Tag
-		if tag == "index" {
+		tag, err := parseTag(f)
+		if err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+		if tag.index {
 			switch kind {
 			case reflect.Struct, reflect.Slice, reflect.Map:
 				return nil, fmt.Errorf("could not index %s field %v %v",
@@ -69,12 +81,26 @@ func New(st interface{}) (*RecordFile, error) {
 	}
 
 	rf := new(RecordFile)
-	fmt.Println("typeRecord:",typeRecord)
 	rf.typeRecord = typeRecord
+	rf.tags = tags
+
+	//index标签的字段同时建立以字段名命名的唯一索引
+	for i := 0; i < typeRecord.NumField(); i++ {
+		f := typeRecord.Field(i)
+		if tags[i].index {
+			rf.defs = append(rf.defs, &indexDef{name: f.Name, fields: []int{i}, unique: true})
+		}
+	}
 
 	return rf, nil
 }
+//按扩展名选择格式读取，见FormatOf
 func (rf *RecordFile) Read(name string) error {
+	return rf.ReadFormat(name, FormatOf(name))
+}
+
+//读取csv格式，Comma为分隔符，第一行为表头
+func (rf *RecordFile) ReadCSV(name string) error {
 	file, err := os.Open(name)
 	//f, _ := os.Open(name)
 	if err != nil {
@@ -98,6 +124,10 @@ func (rf *RecordFile) Read(name string) error {
 	//	}
 	//}
 	//fmt.Println(string(content))
+	return rf.readCSV(file)
+}
+
+func (rf *RecordFile) readCSV(file io.Reader) error {
 	if rf.Comma == 0 {
 		rf.Comma = Comma
 	}
@@ -113,112 +143,118 @@ func (rf *RecordFile) Read(name string) error {
 		return err
 	}
 
+	return rf.readLines(lines, false)
+}
+
+//lines第一行为表头，其余每行为一条记录，byName为true时总是按表头对应列
+func (rf *RecordFile) readLines(lines [][]string, byName bool) error {
 	typeRecord := rf.typeRecord
 
+	if len(lines) == 0 {
+		return errors.New("missing header line")
+	}
+	cols, err := rf.columns(lines[0], byName)
+	if err != nil {
+		return err
+	}
+
 	// make records
 	records := make([]interface{}, len(lines)-1)
 
-	// make indexes
-	indexes := []Index{}
-	for i := 0; i < typeRecord.NumField(); i++ {
-		tag := typeRecord.Field(i).Tag
-		if tag == "index" {
-			indexes = append(indexes, make(Index))
-		}
-	}
-
 	for n := 1; n < len(lines); n++ {
 		value := reflect.New(typeRecord)
 		records[n-1] = value.Interface()
 		record := value.Elem()
 
 		line := lines[n]
-		if len(line) != typeRecord.NumField() {
-			return fmt.Errorf("line %v, field count mismatch: %v (file) %v (st)",
-				n, len(line), typeRecord.NumField())
+		if len(line) != len(lines[0]) {
+			return fmt.Errorf("line %v, field count mismatch: %v (file) %v (header)",
+				n, len(line), len(lines[0]))
 		}
 
-		iIndex := 0
-
 		for i := 0; i < typeRecord.NumField(); i++ {
-			f := typeRecord.Field(i)
-			//fmt.Println("f.tag",f.Tag)
+			tag := rf.tags[i]
 			// records
-			strField := line[i]
+			strField := ""
+			if cols[i] >= 0 {
+				strField = line[cols[i]]
+			}
 			field := record.Field(i)
 			if !field.CanSet() {
 				continue
 			}
 
-			var err error
-
-			kind := f.Type.Kind()
-			if kind == reflect.Bool {
-				var v bool
-				v, err = strconv.ParseBool(strField)
-				if err == nil {
-					field.SetBool(v)
+			if strField == "" {
+				if tag.required {
+					return fmt.Errorf("parse field (row=%v, col=%v) error: %v is required",
+						n, cols[i], tag.name)
 				}
-			} else if kind == reflect.Int ||
-				kind == reflect.Int8 ||
-				kind == reflect.Int16 ||
-				kind == reflect.Int32 ||
-				kind == reflect.Int64 {
-				var v int64
-				v, err = strconv.ParseInt(strField, 0, f.Type.Bits())
-				if err == nil {
-					field.SetInt(v)
+				if tag.hasDefault {
+					strField = tag.def
 				}
-			} else if kind == reflect.Uint ||
-				kind == reflect.Uint8 ||
-				kind == reflect.Uint16 ||
-				kind == reflect.Uint32 ||
-				kind == reflect.Uint64 {
-				var v uint64
-				v, err = strconv.ParseUint(strField, 0, f.Type.Bits())
-				if err == nil {
-					field.SetUint(v)
-				}
-			} else if kind == reflect.Float32 ||
-				kind == reflect.Float64 {
-				var v float64
-				v, err = strconv.ParseFloat(strField, f.Type.Bits())
-				if err == nil {
-					field.SetFloat(v)
-				}
-			} else if kind == reflect.String {
-				field.SetString(strField)
-			} else if kind == reflect.Struct ||
-				kind == reflect.Array ||
-				kind == reflect.Slice ||
-				kind == reflect.Map {
-				err = json.Unmarshal([]byte(strField), field.Addr().Interface())
 			}
-
-			if err != nil {
+			if err := setField(field, strField); err != nil {
 				return fmt.Errorf("parse field (row=%v, col=%v) error: %v",
-					n, i, err)
-			}
-
-			// indexes
-			if f.Tag == "index" {
-				index := indexes[iIndex]
-				iIndex++
-				if _, ok := index[field.Interface()]; ok {
-					return fmt.Errorf("index error: duplicate at (row=%v, col=%v)",
-						n, i)
-				}
-				index[field.Interface()] = records[n-1]
+					n, cols[i], err)
 			}
 		}
 	}
 
+	return rf.setRecords(records)
+}
+
+//建立全部索引，成功后替换数据
+func (rf *RecordFile) setRecords(records []interface{}) error {
+	if err := rf.checkRecords(records); err != nil {
+		return err
+	}
+	indexes, err := rf.makeIndexes(records)
+	if err != nil {
+		return err
+	}
+	named, err := rf.makeNamedIndexes(records)
+	if err != nil {
+		return err
+	}
 	rf.records = records
 	rf.indexes = indexes
+	rf.named = named
 
 	return nil
 }
 
+//按index标签建立索引，records为*st
+func (rf *RecordFile) makeIndexes(records []interface{}) ([]Index, error) {
+	typeRecord := rf.typeRecord
+
+	indexes := []Index{}
+	for i := 0; i < typeRecord.NumField(); i++ {
+		if rf.tags[i].index {
+			indexes = append(indexes, make(Index))
+		}
+	}
+
+	for n, r := range records {
+		record := reflect.ValueOf(r).Elem()
+		iIndex := 0
+		for i := 0; i < typeRecord.NumField(); i++ {
+			if !rf.tags[i].index {
+				continue
+			}
+			index := indexes[iIndex]
+			iIndex++
+			v := record.Field(i).Interface()
+			if _, ok := index[v]; ok {
+				return nil, fmt.Errorf("index error: duplicate at (row=%v, col=%v)",
+					n+1, i)
+			}
+			index[v] = r
+		}
+	}
+
+	return indexes, nil
+}
+
 func (rf *RecordFile) Record(i int) interface{} {
 	return rf.records[i]
 }
@@ -244,12 +280,12 @@ func (rf *RecordFile) Index(i interface{}) interface{} {
 func ReadRfName(st interface{}, name string) *RecordFile {
 	rf, err := New(st)
 	if err != nil {
-		fmt.Println("%v", err)
+		fmt.Printf("%v\n", err)
 	}
 	fn := name + ".txt"
 	err = rf.Read(fn)
 	if err != nil {
-		fmt.Println("%v: %v", fn, err)
+		fmt.Printf("%v: %v\n", fn, err)
 	}
 
 	return rf
@@ -0,0 +1,208 @@
+package Rf
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//数据格式
+const (
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+	FormatYAML   = "yaml"
+	FormatXLSX   = "xlsx"
+	FormatPacked = "bin"
+)
+
+//按扩展名判断格式，.json .yaml .yml .xlsx .bin以外都按csv读取
+func FormatOf(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".xlsx":
+		return FormatXLSX
+	case ".bin":
+		return FormatPacked
+	}
+	return FormatCSV
+}
+
+//按指定格式读取，各种格式都转换为表头加字符串单元格，字段的类型、默认值、校验和索引与csv相同
+func (rf *RecordFile) ReadFormat(name string, format string) error {
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	return rf.readData(name, data, format)
+}
+
+//从文件系统读取，例如embed.FS或者压缩包，格式按扩展名判断
+func (rf *RecordFile) ReadFS(fsys fs.FS, name string) error {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return err
+	}
+	return rf.readData(name, data, FormatOf(name))
+}
+
+func (rf *RecordFile) readData(name string, data []byte, format string) error {
+	var err error
+	var lines [][]string
+	switch format {
+	case FormatCSV:
+		return rf.readCSV(bytes.NewReader(data))
+	case FormatPacked:
+		return rf.readPacked(bytes.NewReader(data))
+	case FormatXLSX:
+		err = rf.readXLSX(bytes.NewReader(data), int64(len(data)), rf.Sheet)
+	case FormatJSON:
+		lines, err = jsonLines(data)
+	case FormatYAML:
+		lines, err = yamlLines(data)
+	default:
+		return fmt.Errorf("unknown format %q", format)
+	}
+	if err == nil && lines != nil {
+		err = rf.readLines(lines, true)
+	}
+	if err != nil {
+		return fmt.Errorf("%v: %v", name, err)
+	}
+	return nil
+}
+
+//按对象的键收集表头，顺序为键第一次出现的顺序
+type objectLines struct {
+	header []string
+	pos    map[string]int
+	rows   []map[int]string
+}
+
+func (o *objectLines) set(row map[int]string, key string, value string) {
+	i, ok := o.pos[key]
+	if !ok {
+		i = len(o.header)
+		o.pos[key] = i
+		o.header = append(o.header, key)
+	}
+	row[i] = value
+}
+
+func (o *objectLines) lines() [][]string {
+	lines := [][]string{o.header}
+	for _, row := range o.rows {
+		line := make([]string, len(o.header))
+		for i, v := range row {
+			line[i] = v
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+//json对象数组，字符串取内容，null为空单元格，数组和对象保留json文本
+func jsonLines(data []byte) ([][]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	o := &objectLines{pos: make(map[string]int)}
+	if err := expectDelim(dec, '['); err != nil {
+		return nil, err
+	}
+	for dec.More() {
+		if err := expectDelim(dec, '{'); err != nil {
+			return nil, fmt.Errorf("row %v: %v", len(o.rows)+1, err)
+		}
+		row := make(map[int]string)
+		for dec.More() {
+			t, err := dec.Token()
+			if err != nil {
+				return nil, err
+			}
+			key := t.(string)
+			var raw json.RawMessage
+			if err := dec.Decode(&raw); err != nil {
+				return nil, err
+			}
+			value := string(raw)
+			switch {
+			case value == "null":
+				value = ""
+			case strings.HasPrefix(value, `"`):
+				if err := json.Unmarshal(raw, &value); err != nil {
+					return nil, err
+				}
+			}
+			o.set(row, key, value)
+		}
+		if err := expectDelim(dec, '}'); err != nil {
+			return nil, err
+		}
+		o.rows = append(o.rows, row)
+	}
+	if err := expectDelim(dec, ']'); err != nil {
+		return nil, err
+	}
+	return o.lines(), nil
+}
+
+func expectDelim(dec *json.Decoder, delim json.Delim) error {
+	t, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if t != delim {
+		return fmt.Errorf("expected %v, got %v", delim, t)
+	}
+	return nil
+}
+
+//yaml映射的序列，标量取文本，null为空单元格，序列和映射转换为json文本
+func yamlLines(data []byte) ([][]string, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	o := &objectLines{pos: make(map[string]int)}
+	if len(doc.Content) == 0 {
+		return o.lines(), nil
+	}
+	seq := doc.Content[0]
+	if seq.Kind != yaml.SequenceNode {
+		return nil, fmt.Errorf("line %v: sequence required", seq.Line)
+	}
+	for _, m := range seq.Content {
+		if m.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("line %v: mapping required", m.Line)
+		}
+		row := make(map[int]string)
+		for i := 0; i+1 < len(m.Content); i += 2 {
+			key, node := m.Content[i], m.Content[i+1]
+			value := ""
+			switch {
+			case node.Kind == yaml.ScalarNode && node.Tag != "!!null":
+				value = node.Value
+			case node.Kind != yaml.ScalarNode:
+				var v interface{}
+				if err := node.Decode(&v); err != nil {
+					return nil, err
+				}
+				b, err := json.Marshal(v)
+				if err != nil {
+					return nil, fmt.Errorf("line %v: %v", node.Line, err)
+				}
+				value = string(b)
+			}
+			o.set(row, key.Value, value)
+		}
+		o.rows = append(o.rows, row)
+	}
+	return o.lines(), nil
+}
@@ -0,0 +1,210 @@
+package Rf
+
+import (
+	"archive/zip"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+type sourceItem struct {
+	Id    int `rf:"index"`
+	Name  string
+	Tags  []int
+	Valid bool
+	Level int `rf:"default=1"`
+}
+
+func writeTemp(t *testing.T, dir string, name string, content string) string {
+	name = filepath.Join(dir, name)
+	if err := ioutil.WriteFile(name, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return name
+}
+
+//生成只有一个sheet的xlsx，第一列使用共享字符串，其余使用内联字符串
+func writeXLSX(t *testing.T, name string, rows [][]string) {
+	f, err := os.Create(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	w := zip.NewWriter(f)
+	files := map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+			`<sheets><sheet name="Item" sheetId="1" r:id="rId1"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/></Relationships>`,
+	}
+	var shared, sheet strings.Builder
+	for n, row := range rows {
+		fmt.Fprintf(&sheet, `<row r="%v">`, n+1)
+		for i, v := range row {
+			if v == "" {
+				continue
+			}
+			ref := fmt.Sprintf("%c%v", 'A'+i, n+1)
+			if i == 0 {
+				fmt.Fprintf(&shared, "<si><t>%v</t></si>", v)
+				fmt.Fprintf(&sheet, `<c r="%v" t="s"><v>%v</v></c>`, ref, strings.Count(shared.String(), "<si>")-1)
+			} else {
+				fmt.Fprintf(&sheet, `<c r="%v" t="inlineStr"><is><t>%v</t></is></c>`, ref, v)
+			}
+		}
+		sheet.WriteString("</row>")
+	}
+	files["xl/sharedStrings.xml"] = "<sst>" + shared.String() + "</sst>"
+	files["xl/worksheets/sheet1.xml"] = "<worksheet><sheetData>" + sheet.String() + "</sheetData></worksheet>"
+	for name, content := range files {
+		fw, err := w.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fw.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestSources(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := []string{
+		writeTemp(t, dir, "item.txt", "Id,Name,Tags,Valid,Level\n"+
+			`1,sword,"[1,2]",true,1`+"\n"+
+			"2,shield,[],false,3\n"),
+		writeTemp(t, dir, "item.json", `[
+	{"id": 1, "name": "sword", "tags": [1, 2], "valid": true},
+	{"id": 2, "name": "shield", "tags": [], "valid": false, "level": 3}
+]`),
+		writeTemp(t, dir, "item.yaml", `
+- {id: 1, name: sword, tags: [1, 2], valid: true, level: null}
+- id: 2
+  name: shield
+  tags: []
+  valid: false
+  level: 3
+`),
+	}
+	xlsx := filepath.Join(dir, "item.xlsx")
+	writeXLSX(t, xlsx, [][]string{
+		{"编号", "名字"},
+		{"#"},
+		{"id", "name", "tags", "valid", "level"},
+		{"int", "string", "[]int", "bool", "int"},
+		{"1", "sword", "[1,2]", "TRUE"},
+		{},
+		{"2", "shield", "[]", "FALSE", "3"},
+	})
+	files = append(files, xlsx)
+
+	want := []*sourceItem{
+		{Id: 1, Name: "sword", Tags: []int{1, 2}, Valid: true, Level: 1},
+		{Id: 2, Name: "shield", Tags: []int{}, Valid: false, Level: 3},
+	}
+	for _, name := range files {
+		rf, err := New(sourceItem{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rf.Read(name); err != nil {
+			t.Errorf("%v: %v", filepath.Base(name), err)
+			continue
+		}
+		if rf.NumRecord() != len(want) {
+			t.Errorf("%v: got %v records", filepath.Base(name), rf.NumRecord())
+			continue
+		}
+		for i, w := range want {
+			if got := rf.Record(i); !reflect.DeepEqual(got, w) {
+				t.Errorf("%v: got %+v, want %+v", filepath.Base(name), got, w)
+			}
+		}
+		if r, ok := rf.Index(2).(*sourceItem); !ok || r.Name != "shield" {
+			t.Errorf("%v: index not built", filepath.Base(name))
+		}
+	}
+}
+
+func TestReadXLSXSheets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	name := filepath.Join(dir, "item.xlsx")
+	writeXLSX(t, name, [][]string{{"id", "name"}, {}, {"1", "", "x"}})
+	sheets, err := ReadXLSXSheets(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []XLSXSheet{{Name: "Item", Rows: [][]string{{"id", "name"}, nil, {"1", "", "x"}}}}
+	if !reflect.DeepEqual(sheets, want) {
+		t.Errorf("got %q, want %q", sheets, want)
+	}
+	if _, err := ReadXLSXSheets(filepath.Join(dir, "missing.xlsx")); err == nil {
+		t.Error("expected missing file error")
+	}
+}
+
+func TestSourceErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "rf")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	contents := map[string]string{
+		"bad.json":  `{"id": 1}`,
+		"type.json": `[{"id": "x", "name": "a", "tags": [], "valid": true}]`,
+		"bad.yaml":  "id: 1\n",
+		"dup.yaml":  "- {id: 1, name: a, tags: [], valid: true}\n- {id: 1, name: b, tags: [], valid: true}\n",
+	}
+	for name, content := range contents {
+		rf, err := New(sourceItem{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rf.Read(writeTemp(t, dir, name, content)); err == nil {
+			t.Errorf("%v: expected error", name)
+		}
+	}
+	rf, _ := New(sourceItem{})
+	if err := rf.ReadFormat(filepath.Join(dir, "bad.json"), "toml"); err == nil {
+		t.Error("expected unknown format error")
+	}
+}
+
+func TestReadFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"data/item.txt":  {Data: []byte("Id,Name,Tags,Valid,Level\n1,sword,[],true,2\n")},
+		"data/item.json": {Data: []byte(`[{"id": 1, "name": "sword", "tags": [], "valid": true, "level": 2}]`)},
+	}
+	for _, name := range []string{"data/item.txt", "data/item.json"} {
+		rf, err := New(sourceItem{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := rf.ReadFS(fsys, name); err != nil {
+			t.Fatalf("%v: %v", name, err)
+		}
+		if r, ok := rf.Index(1).(*sourceItem); !ok || r.Level != 2 {
+			t.Errorf("%v: got %+v", name, rf.Record(0))
+		}
+	}
+	rf, _ := New(sourceItem{})
+	if err := rf.ReadFS(fsys, "data/missing.txt"); err == nil {
+		t.Error("expected missing file error")
+	}
+}
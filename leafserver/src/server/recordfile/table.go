@@ -0,0 +1,140 @@
+package Rf
+
+import (
+	"fmt"
+)
+
+//类型化的配置表，记录在读取时转换为*T，查询时不需要类型断言
+type Table[T any] struct {
+	rf      *RecordFile
+	records []*T
+	key     int //第一个index标签字段，-1表示没有
+}
+
+//T必须为结构体，index标签与RecordFile相同
+func NewTable[T any]() (*Table[T], error) {
+	var st T
+	rf, err := New(st)
+	if err != nil {
+		return nil, err
+	}
+	t := &Table[T]{rf: rf, key: -1}
+	for i, tag := range rf.tags {
+		if tag.index {
+			t.key = i
+			break
+		}
+	}
+	return t, nil
+}
+
+//创建并读取配置表
+func ReadTable[T any](name string) (*Table[T], error) {
+	t, err := NewTable[T]()
+	if err != nil {
+		return nil, err
+	}
+	if err := t.Read(name); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Table[T]) Read(name string) error {
+	if err := t.rf.Read(name); err != nil {
+		return err
+	}
+	return t.load()
+}
+
+func (t *Table[T]) ReadPacked(name string) error {
+	if err := t.rf.ReadPacked(name); err != nil {
+		return err
+	}
+	return t.load()
+}
+
+func (t *Table[T]) load() error {
+	records := make([]*T, t.rf.NumRecord())
+	for i := range records {
+		r, ok := t.rf.Record(i).(*T)
+		if !ok {
+			return fmt.Errorf("record %v is %T, not %T", i, t.rf.Record(i), r)
+		}
+		records[i] = r
+	}
+	t.records = records
+	return nil
+}
+
+//底层的RecordFile，用于添加命名索引
+func (t *Table[T]) RecordFile() *RecordFile {
+	return t.rf
+}
+
+//按第一个index标签字段查找，key会转换为字段类型
+func (t *Table[T]) Get(key interface{}) (*T, bool) {
+	if t.key < 0 {
+		return nil, false
+	}
+	k, err := convertKey(key, t.rf.typeRecord.Field(t.key).Type)
+	if err != nil {
+		return nil, false
+	}
+	r, ok := t.rf.Index(k.Interface()).(*T)
+	return r, ok
+}
+
+//按命名索引查找，索引不存在时返回false
+func (t *Table[T]) Lookup(name string, keys ...interface{}) (*T, bool) {
+	records := t.LookupAll(name, keys...)
+	if len(records) == 0 {
+		return nil, false
+	}
+	return records[0], true
+}
+
+func (t *Table[T]) LookupAll(name string, keys ...interface{}) []*T {
+	if t.rf.named[name] == nil {
+		return nil
+	}
+	return typed[T](t.rf.LookupAll(name, keys...))
+}
+
+func (t *Table[T]) Range(name string, min interface{}, max interface{}) []*T {
+	if index := t.rf.named[name]; index == nil || !index.def.sorted {
+		return nil
+	}
+	return typed[T](t.rf.Range(name, min, max))
+}
+
+func typed[T any](records []interface{}) []*T {
+	result := make([]*T, len(records))
+	for i, r := range records {
+		result[i] = r.(*T)
+	}
+	return result
+}
+
+func (t *Table[T]) Record(i int) *T {
+	return t.records[i]
+}
+
+//全部记录，按读取顺序，调用方不能修改
+func (t *Table[T]) All() []*T {
+	return t.records
+}
+
+func (t *Table[T]) Filter(f func(r *T) bool) []*T {
+	var result []*T
+	for _, r := range t.records {
+		if f(r) {
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+func (t *Table[T]) Len() int {
+	return len(t.records)
+}
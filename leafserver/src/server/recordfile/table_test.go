@@ -0,0 +1,49 @@
+package Rf
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestTable(t *testing.T) {
+	if _, err := NewTable[int](); err == nil {
+		t.Error("expected error for non-struct table")
+	}
+
+	f, err := ioutil.TempFile("", "reward")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString("Id,Chapter,Level,Class,Weight\n" +
+		"1,3,10,warrior,0.5\n" +
+		"2,1,20,mage,1.5\n")
+	f.Close()
+
+	table, err := ReadTable[reward](f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Len() != 2 || table.Record(1).Class != "mage" {
+		t.Errorf("got %+v", table.All())
+	}
+	if _, ok := table.Get(1); ok {
+		t.Error("Get without index field should fail")
+	}
+	got := table.Filter(func(r *reward) bool { return r.Chapter == 3 })
+	if want := []*reward{table.Record(0)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Filter: %v", got)
+	}
+
+	if err := table.RecordFile().AddMultiIndex("Chapter", "Chapter"); err != nil {
+		t.Fatal(err)
+	}
+	if r, ok := table.Lookup("Chapter", 1); !ok || r.Id != 2 {
+		t.Errorf("Lookup: %v %v", r, ok)
+	}
+	if _, ok := table.Lookup("Missing", 1); ok {
+		t.Error("Lookup on missing index should fail")
+	}
+}
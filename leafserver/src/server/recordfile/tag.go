@@ -0,0 +1,261 @@
+package Rf
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//结构化标签，选项用逗号分隔
+//
+//	Id    int    `rf:"index"`
+//	Level int    `rf:"required,min=1,max=100,name=Lv"`
+//	Class string `rf:"default=warrior,enum=warrior|mage"`
+//
+//name为列名，任意字段声明了name或者表头包含全部列名时按表头对应列，否则按位置对应，
+//json、yaml、xlsx总是按表头对应列
+//default为空单元格的值，required表示单元格不能为空，min、max只能用于数值字段
+//兼容leaf原来的 "index" 标签
+type fieldTag struct {
+	index      bool
+	required   bool
+	name       string
+	named      bool //显式声明了name
+	hasDefault bool
+	def        string
+	hasMin     bool
+	hasMax     bool
+	min        float64
+	max        float64
+	enum       []string
+}
+
+func parseTag(f reflect.StructField) (*fieldTag, error) {
+	tag := &fieldTag{name: f.Name}
+	if f.Tag == "index" {
+		tag.index = true
+		return tag, nil
+	}
+	value, ok := f.Tag.Lookup("rf")
+	if !ok {
+		return tag, nil
+	}
+	for _, opt := range strings.Split(value, ",") {
+		key, arg, hasArg := strings.Cut(strings.TrimSpace(opt), "=")
+		var err error
+		switch key {
+		case "":
+			continue
+		case "index":
+			tag.index = true
+		case "required":
+			tag.required = true
+		case "name":
+			tag.name, tag.named = arg, true
+		case "default":
+			tag.def, tag.hasDefault = arg, true
+		case "min":
+			tag.min, err = strconv.ParseFloat(arg, 64)
+			tag.hasMin = true
+		case "max":
+			tag.max, err = strconv.ParseFloat(arg, 64)
+			tag.hasMax = true
+		case "enum":
+			tag.enum = strings.Split(arg, "|")
+		default:
+			return nil, fmt.Errorf("field %v: unknown rf option %q", f.Name, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("field %v: invalid rf option %q: %v", f.Name, opt, err)
+		}
+		switch key {
+		case "name", "default", "min", "max", "enum":
+			if !hasArg || arg == "" && key != "default" {
+				return nil, fmt.Errorf("field %v: rf option %v requires a value", f.Name, key)
+			}
+		default:
+			if hasArg {
+				return nil, fmt.Errorf("field %v: rf option %v takes no value", f.Name, key)
+			}
+		}
+	}
+
+	if (tag.hasMin || tag.hasMax) && numberClass(f.Type.Kind()) == 0 {
+		return nil, fmt.Errorf("field %v: min and max require a numeric field", f.Name)
+	}
+	if tag.hasMin && tag.hasMax && tag.min > tag.max {
+		return nil, fmt.Errorf("field %v: min %v greater than max %v", f.Name, tag.min, tag.max)
+	}
+	if tag.hasDefault {
+		v := reflect.New(f.Type).Elem()
+		if err := setField(v, tag.def); err != nil {
+			return nil, fmt.Errorf("field %v: invalid default %q: %v", f.Name, tag.def, err)
+		}
+		if err := tag.check(v); err != nil {
+			return nil, fmt.Errorf("field %v: invalid default %q: %v", f.Name, tag.def, err)
+		}
+	}
+	return tag, nil
+}
+
+//按字段类型解析单元格
+func setField(field reflect.Value, strField string) error {
+	var err error
+
+	kind := field.Kind()
+	if kind == reflect.Bool {
+		var v bool
+		v, err = strconv.ParseBool(strField)
+		if err == nil {
+			field.SetBool(v)
+		}
+	} else if kind == reflect.Int ||
+		kind == reflect.Int8 ||
+		kind == reflect.Int16 ||
+		kind == reflect.Int32 ||
+		kind == reflect.Int64 {
+		var v int64
+		v, err = strconv.ParseInt(strField, 0, field.Type().Bits())
+		if err == nil {
+			field.SetInt(v)
+		}
+	} else if kind == reflect.Uint ||
+		kind == reflect.Uint8 ||
+		kind == reflect.Uint16 ||
+		kind == reflect.Uint32 ||
+		kind == reflect.Uint64 {
+		var v uint64
+		v, err = strconv.ParseUint(strField, 0, field.Type().Bits())
+		if err == nil {
+			field.SetUint(v)
+		}
+	} else if kind == reflect.Float32 ||
+		kind == reflect.Float64 {
+		var v float64
+		v, err = strconv.ParseFloat(strField, field.Type().Bits())
+		if err == nil {
+			field.SetFloat(v)
+		}
+	} else if kind == reflect.String {
+		field.SetString(strField)
+	} else if kind == reflect.Struct ||
+		kind == reflect.Array ||
+		kind == reflect.Slice ||
+		kind == reflect.Map {
+		err = json.Unmarshal([]byte(strField), field.Addr().Interface())
+	}
+
+	return err
+}
+
+//检查字段值的范围和枚举
+func (tag *fieldTag) check(field reflect.Value) error {
+	if tag.hasMin || tag.hasMax {
+		var v float64
+		switch numberClass(field.Kind()) {
+		case 1:
+			v = float64(field.Int())
+		case 2:
+			v = float64(field.Uint())
+		default:
+			v = field.Float()
+		}
+		if tag.hasMin && v < tag.min || tag.hasMax && v > tag.max {
+			return fmt.Errorf("value %v out of range [%v, %v]", v, tag.rangeMin(), tag.rangeMax())
+		}
+	}
+	if tag.enum != nil {
+		s := fmt.Sprint(field.Interface())
+		for _, e := range tag.enum {
+			if s == e {
+				return nil
+			}
+		}
+		return fmt.Errorf("value %q not in %v", s, strings.Join(tag.enum, "|"))
+	}
+	return nil
+}
+
+func (tag *fieldTag) rangeMin() string {
+	if tag.hasMin {
+		return fmt.Sprint(tag.min)
+	}
+	return "-inf"
+}
+
+func (tag *fieldTag) rangeMax() string {
+	if tag.hasMax {
+		return fmt.Sprint(tag.max)
+	}
+	return "+inf"
+}
+
+//检查全部记录，行号从1开始，列为列名
+func (rf *RecordFile) checkRecords(records []interface{}) error {
+	for n, r := range records {
+		record := reflect.ValueOf(r).Elem()
+		for i, tag := range rf.tags {
+			if err := tag.check(record.Field(i)); err != nil {
+				return fmt.Errorf("check field (row=%v, col=%v) error: %v",
+					n+1, tag.name, err)
+			}
+		}
+	}
+	return nil
+}
+
+//字段对应的列，表头按列名对应时返回每个字段在表头中的位置，没有的列为-1
+//没有声明name的字段也可以对应首字母小写的列名，与ReadPacked相同
+func (rf *RecordFile) columns(header []string, byName bool) ([]int, error) {
+	cols := make([]int, len(rf.tags))
+	for _, tag := range rf.tags {
+		byName = byName || tag.named
+	}
+	pos := make(map[string]int, len(header))
+	exported := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.TrimSpace(name)
+		pos[name] = i
+		if _, ok := exported[exportedName(name)]; !ok && name != "" {
+			exported[exportedName(name)] = i
+		}
+	}
+	find := func(tag *fieldTag) (int, bool) {
+		col, ok := pos[tag.name]
+		if !ok && !tag.named {
+			col, ok = exported[tag.name]
+		}
+		return col, ok
+	}
+	if !byName {
+		byName = true
+		for _, tag := range rf.tags {
+			if _, ok := find(tag); !ok {
+				byName = false
+				break
+			}
+		}
+	}
+
+	for i, tag := range rf.tags {
+		if !byName {
+			cols[i] = i
+			continue
+		}
+		col, ok := find(tag)
+		if !ok && !tag.hasDefault {
+			return nil, fmt.Errorf("column %v not found", tag.name)
+		}
+		if !ok {
+			col = -1
+		}
+		cols[i] = col
+	}
+	if !byName && len(header) != len(rf.tags) {
+		return nil, fmt.Errorf("line 0, field count mismatch: %v (file) %v (st)",
+			len(header), len(rf.tags))
+	}
+	return cols, nil
+}
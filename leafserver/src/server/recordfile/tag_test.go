@@ -0,0 +1,95 @@
+package Rf
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+type hero struct {
+	Id    int    `rf:"index,required"`
+	Level int    `rf:"min=1,max=100,default=1,name=Lv"`
+	Class string `rf:"default=warrior,enum=warrior|mage"`
+	Note  string
+}
+
+func readString(rf *RecordFile, content string) error {
+	f, err := ioutil.TempFile("", "rf")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(content)
+	f.Close()
+	return rf.Read(f.Name())
+}
+
+func TestTag(t *testing.T) {
+	rf, err := New(hero{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	//按表头对应列，Class列缺失时使用默认值
+	err = readString(rf, "Note,Id,Lv\n"+
+		"first,1,10\n"+
+		"second,2,\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r := rf.Index(1).(*hero); r.Level != 10 || r.Class != "warrior" || r.Note != "first" {
+		t.Errorf("got %+v", r)
+	}
+	if r := rf.Index(2).(*hero); r.Level != 1 {
+		t.Errorf("default not applied: %+v", r)
+	}
+
+	errs := map[string]string{
+		"Id,Lv,Class,Note\n,1,mage,x\n":     "Id is required",
+		"Id,Lv,Class,Note\n1,0,mage,x\n":    "(row=1, col=Lv) error: value 0 out of range [1, 100]",
+		"Id,Lv,Class,Note\n1,1,priest,x\n":  `value "priest" not in warrior|mage`,
+		"Id,Class,Note\n1,mage,x\n2,mage,y": "",
+		"Id,Lv,Class\n1,1,mage\n":           "column Note not found",
+	}
+	for content, want := range errs {
+		err := readString(rf, content)
+		if want == "" {
+			if err != nil {
+				t.Errorf("%q: %v", content, err)
+			}
+			continue
+		}
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("%q: got %v, want %v", content, err, want)
+		}
+	}
+}
+
+func TestTagError(t *testing.T) {
+	sts := []interface{}{
+		struct {
+			Name string `rf:"min=1"`
+		}{},
+		struct {
+			Id int `rf:"default=x"`
+		}{},
+		struct {
+			Id int `rf:"max=10,default=20"`
+		}{},
+		struct {
+			Id int `rf:"unique"`
+		}{},
+		struct {
+			Id int `rf:"name"`
+		}{},
+		struct {
+			Id    int
+			class string `rf:"name=Class,enum=warrior|mage"`
+		}{},
+	}
+	for _, st := range sts {
+		if _, err := New(st); err == nil {
+			t.Errorf("%T: expected error", st)
+		}
+	}
+}
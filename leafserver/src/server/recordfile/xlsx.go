@@ -0,0 +1,279 @@
+package Rf
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+//xlsx表格行定义，与excel2json相同，下标从0开始
+var (
+	XlsxFieldRow = 2 //字段名行
+	XlsxDataRow  = 4 //数据起始行
+)
+
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		Id   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Relationships []struct {
+		Id     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+type xlsxText struct {
+	T string `xml:"t"`
+	R []struct {
+		T string `xml:"t"`
+	} `xml:"r"`
+}
+
+func (t *xlsxText) String() string {
+	if len(t.R) == 0 {
+		return t.T
+	}
+	var b strings.Builder
+	for _, r := range t.R {
+		b.WriteString(r.T)
+	}
+	return b.String()
+}
+
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		R     int `xml:"r,attr"`
+		Cells []struct {
+			R  string   `xml:"r,attr"`
+			T  string   `xml:"t,attr"`
+			V  string   `xml:"v"`
+			Is xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+//读取xlsx中的一个sheet，sheet为空时读取第一个，XlsxFieldRow为表头，从XlsxDataRow开始为数据，空行跳过
+func (rf *RecordFile) ReadXLSX(name string, sheet string) error {
+	file, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	if err := rf.readXLSX(file, info.Size(), sheet); err != nil {
+		return fmt.Errorf("%v: %v", name, err)
+	}
+	return nil
+}
+
+func (rf *RecordFile) readXLSX(ra io.ReaderAt, size int64, sheet string) error {
+	rows, err := readXLSX(ra, size, sheet)
+	if err != nil {
+		return err
+	}
+	if len(rows) <= XlsxFieldRow {
+		return errors.New("missing field row")
+	}
+
+	header := rows[XlsxFieldRow]
+	lines := [][]string{header}
+	for n := XlsxDataRow; n < len(rows); n++ {
+		line := make([]string, len(header))
+		empty := true
+		for i, v := range rows[n] {
+			if i < len(line) {
+				line[i] = v
+			}
+			empty = empty && strings.TrimSpace(v) == ""
+		}
+		if !empty {
+			lines = append(lines, line)
+		}
+	}
+	return rf.readLines(lines, true)
+}
+
+//xlsx中的一个sheet
+type XLSXSheet struct {
+	Name string
+	Rows [][]string
+}
+
+//按表格中的顺序读取全部sheet的单元格文本
+func ReadXLSXSheets(name string) ([]XLSXSheet, error) {
+	file, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	x, err := openXLSX(file, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", name, err)
+	}
+	sheets := make([]XLSXSheet, 0, len(x.workbook.Sheets))
+	for _, s := range x.workbook.Sheets {
+		rows, err := x.rows(s.Id)
+		if err != nil {
+			return nil, fmt.Errorf("%v[%v]: %v", name, s.Name, err)
+		}
+		sheets = append(sheets, XLSXSheet{Name: s.Name, Rows: rows})
+	}
+	return sheets, nil
+}
+
+type xlsxFile struct {
+	files    map[string]*zip.File
+	workbook xlsxWorkbook
+	rels     xlsxRelationships
+	shared   xlsxSharedStrings
+}
+
+func openXLSX(ra io.ReaderAt, size int64) (*xlsxFile, error) {
+	r, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	x := &xlsxFile{files: make(map[string]*zip.File, len(r.File))}
+	for _, f := range r.File {
+		x.files[f.Name] = f
+	}
+
+	if err := decodeXML(x.files, "xl/workbook.xml", &x.workbook); err != nil {
+		return nil, err
+	}
+	if err := decodeXML(x.files, "xl/_rels/workbook.xml.rels", &x.rels); err != nil {
+		return nil, err
+	}
+	if x.files["xl/sharedStrings.xml"] != nil {
+		if err := decodeXML(x.files, "xl/sharedStrings.xml", &x.shared); err != nil {
+			return nil, err
+		}
+	}
+	return x, nil
+}
+
+//读取sheet的全部单元格文本
+func readXLSX(ra io.ReaderAt, size int64, sheet string) ([][]string, error) {
+	x, err := openXLSX(ra, size)
+	if err != nil {
+		return nil, err
+	}
+	id := ""
+	for _, s := range x.workbook.Sheets {
+		if sheet == "" || s.Name == sheet {
+			id = s.Id
+			break
+		}
+	}
+	if id == "" {
+		return nil, fmt.Errorf("sheet %q not found", sheet)
+	}
+	return x.rows(id)
+}
+
+//id为workbook中sheet的关系id
+func (x *xlsxFile) rows(id string) ([][]string, error) {
+	target := ""
+	for _, rel := range x.rels.Relationships {
+		if rel.Id == id {
+			target = rel.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = target[1:]
+	} else {
+		target = path.Join("xl", target)
+	}
+	var data xlsxSheet
+	if err := decodeXML(x.files, target, &data); err != nil {
+		return nil, err
+	}
+
+	var err error
+	var rows [][]string
+	for _, row := range data.Rows {
+		n := len(rows)
+		if row.R > 0 {
+			n = row.R - 1
+		}
+		for len(rows) <= n {
+			rows = append(rows, nil)
+		}
+		for _, c := range row.Cells {
+			col := len(rows[n])
+			if c.R != "" {
+				if col, err = cellColumn(c.R); err != nil {
+					return nil, err
+				}
+			}
+			for len(rows[n]) <= col {
+				rows[n] = append(rows[n], "")
+			}
+			v := c.V
+			switch c.T {
+			case "s":
+				i, err := strconv.Atoi(v)
+				if err != nil || i < 0 || i >= len(x.shared.Items) {
+					return nil, fmt.Errorf("cell %v: invalid shared string %q", c.R, v)
+				}
+				v = x.shared.Items[i].String()
+			case "inlineStr":
+				v = c.Is.String()
+			case "b":
+				v = strings.ToUpper(strconv.FormatBool(v == "1"))
+			}
+			rows[n][col] = v
+		}
+	}
+	return rows, nil
+}
+
+func decodeXML(files map[string]*zip.File, name string, v interface{}) error {
+	f := files[name]
+	if f == nil {
+		return fmt.Errorf("%v not found", name)
+	}
+	r, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	if err := xml.NewDecoder(r).Decode(v); err != nil && err != io.EOF {
+		return fmt.Errorf("%v: %v", name, err)
+	}
+	return nil
+}
+
+//单元格坐标的列，A1为0
+func cellColumn(ref string) (int, error) {
+	col := 0
+	i := 0
+	for ; i < len(ref) && ref[i] >= 'A' && ref[i] <= 'Z'; i++ {
+		col = col*26 + int(ref[i]-'A') + 1
+	}
+	if i == 0 {
+		return 0, fmt.Errorf("invalid cell %q", ref)
+	}
+	return col - 1, nil
+}
@@ -0,0 +1,128 @@
+package session
+
+import (
+	"sync"
+
+	"github.com/name5566/leaf/gate"
+)
+
+// 通过认证的连接，Data 由使用者保存玩家数据
+type Session struct {
+	UserID string
+	Agent  gate.Agent
+	Data   interface{}
+}
+
+// 用户 id 和 agent 的双向索引，可以在任何 goroutine 中使用
+type Manager struct {
+	// 踢下线前发送给客户端的消息，为 nil 时直接关闭连接
+	KickMsg func(reason string) interface{}
+
+	mu      sync.RWMutex
+	byID    map[string]*Session
+	byAgent map[gate.Agent]*Session
+}
+
+func NewManager(kickMsg func(reason string) interface{}) *Manager {
+	return &Manager{
+		KickMsg: kickMsg,
+		byID:    make(map[string]*Session),
+		byAgent: make(map[gate.Agent]*Session),
+	}
+}
+
+// 绑定用户 id 和 agent，同一个用户已经在其他连接上登录时踢掉旧的连接
+func (m *Manager) Bind(userID string, a gate.Agent) *Session {
+	m.mu.Lock()
+	old := m.byID[userID]
+	if old != nil && old.Agent == a {
+		m.mu.Unlock()
+		return old
+	}
+	if s := m.byAgent[a]; s != nil {
+		delete(m.byID, s.UserID)
+	}
+	if old != nil {
+		delete(m.byAgent, old.Agent)
+	}
+	s := &Session{UserID: userID, Agent: a}
+	m.byID[userID] = s
+	m.byAgent[a] = s
+	m.mu.Unlock()
+
+	if old != nil {
+		m.kick(old, "duplicate login")
+	}
+	return s
+}
+
+// 连接关闭时调用，返回解除绑定的会话，没有绑定时为 nil
+func (m *Manager) Unbind(a gate.Agent) *Session {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s := m.byAgent[a]
+	if s == nil {
+		return nil
+	}
+	delete(m.byAgent, a)
+	delete(m.byID, s.UserID)
+	return s
+}
+
+func (m *Manager) Get(userID string) *Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.byID[userID]
+}
+
+func (m *Manager) ByAgent(a gate.Agent) *Session {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.byAgent[a]
+}
+
+func (m *Manager) Len() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return len(m.byID)
+}
+
+// 遍历全部会话，f 返回 false 时停止，遍历的是调用时的快照，f 中可以踢人
+func (m *Manager) Range(f func(s *Session) bool) {
+	m.mu.RLock()
+	sessions := make([]*Session, 0, len(m.byID))
+	for _, s := range m.byID {
+		sessions = append(sessions, s)
+	}
+	m.mu.RUnlock()
+
+	for _, s := range sessions {
+		if !f(s) {
+			return
+		}
+	}
+}
+
+// 发送原因后关闭连接，用户不在线时返回 false
+func (m *Manager) Kick(userID string, reason string) bool {
+	m.mu.Lock()
+	s := m.byID[userID]
+	if s != nil {
+		delete(m.byID, userID)
+		delete(m.byAgent, s.Agent)
+	}
+	m.mu.Unlock()
+
+	if s == nil {
+		return false
+	}
+	m.kick(s, reason)
+	return true
+}
+
+func (m *Manager) kick(s *Session, reason string) {
+	if m.KickMsg != nil {
+		s.Agent.WriteMsg(m.KickMsg(reason))
+	}
+	s.Agent.Close()
+}
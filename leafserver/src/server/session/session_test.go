@@ -0,0 +1,66 @@
+package session
+
+import (
+	"net"
+	"sort"
+	"testing"
+)
+
+type testAgent struct {
+	msgs   []interface{}
+	closed bool
+}
+
+func (a *testAgent) WriteMsg(msg interface{})     { a.msgs = append(a.msgs, msg) }
+func (a *testAgent) LocalAddr() net.Addr          { return nil }
+func (a *testAgent) RemoteAddr() net.Addr         { return nil }
+func (a *testAgent) Close()                       { a.closed = true }
+func (a *testAgent) Destroy()                     { a.closed = true }
+func (a *testAgent) UserData() interface{}        { return nil }
+func (a *testAgent) SetUserData(data interface{}) {}
+
+func TestManager(t *testing.T) {
+	m := NewManager(func(reason string) interface{} { return reason })
+	a1, a2, b := new(testAgent), new(testAgent), new(testAgent)
+	m.Bind("a", a1)
+	m.Bind("b", b)
+	if s := m.Get("a"); s == nil || s.Agent != a1 || m.ByAgent(b).UserID != "b" {
+		t.Fatalf("got %+v", s)
+	}
+
+	//重复登录踢掉旧连接
+	m.Bind("a", a2)
+	if !a1.closed || len(a1.msgs) != 1 || a1.msgs[0] != "duplicate login" {
+		t.Errorf("old agent %+v", a1)
+	}
+	if m.Get("a").Agent != a2 || m.Len() != 2 {
+		t.Errorf("got %+v, len %v", m.Get("a"), m.Len())
+	}
+	//旧连接关闭时不影响新连接
+	if s := m.Unbind(a1); s != nil {
+		t.Errorf("unbind old agent got %+v", s)
+	}
+	if m.Get("a") == nil {
+		t.Error("new session removed")
+	}
+
+	var ids []string
+	m.Range(func(s *Session) bool {
+		ids = append(ids, s.UserID)
+		return true
+	})
+	sort.Strings(ids)
+	if len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Errorf("range got %v", ids)
+	}
+
+	if !m.Kick("b", "banned") || !b.closed || b.msgs[0] != "banned" {
+		t.Errorf("kick got %+v", b)
+	}
+	if m.Kick("b", "banned") || m.Get("b") != nil || m.Len() != 1 {
+		t.Error("kicked session still bound")
+	}
+	if s := m.Unbind(a2); s == nil || s.UserID != "a" || m.Len() != 0 {
+		t.Errorf("unbind got %+v", s)
+	}
+}